@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,158 +18,257 @@ import (
 
 const port = ":8080"
 
-// liveReloadSnippet is injected before </body> in every HTML response.
-// It opens an SSE connection to /dev-reload. When the connection drops
-// (because the server is restarting), it polls until the new server is up,
-// then reloads the page.
-const liveReloadSnippet = `<script>
+const (
+	configDir = "internal/templates/contents"
+	blogDir   = "blog"
+)
+
+// devBuildFile records the ID and output digests of the last build, so the server
+// process air starts after a change can tell pages from the previous build what
+// changed, even though neither build was written to disk.
+const devBuildFile = ".cache/dev-build.json"
+
+// liveReloadScript is injected before </body> in every HTML response, with
+// __BUILD_ID__ replaced by the build the page came from. It listens on
+// /dev-reload, which replays the events of a rebuild to pages from the build
+// before it: css-changed swaps stylesheet links in place, page-changed reloads
+// only if the current page is among the rebuilt outputs, and reload reloads
+// unconditionally. When the connection drops during a restart it reconnects
+// with the ID of the build it last saw.
+const liveReloadScript = `<script>
 (() => {
+	let build = '__BUILD_ID__';
+	const unfingerprint = (p) => p.replace(/\.[0-9a-f]{8}(\.[A-Za-z0-9]+)$/, '$1');
+	function currentPage() {
+		const p = decodeURIComponent(location.pathname).replace(/^\/+/, '');
+		if (p === '' || p.endsWith('/')) return p + 'index.html';
+		return /\.[A-Za-z0-9]+$/.test(p) ? p : p + '.html';
+	}
+	function swapStylesheets(assets) {
+		document.querySelectorAll('link[rel="stylesheet"]').forEach((link) => {
+			const name = unfingerprint(new URL(link.href).pathname.replace(/^\/+/, ''));
+			if (!assets[name]) return;
+			const next = link.cloneNode();
+			// Only the file name carries the hash, so resolve it against the old link.
+			next.href = new URL(assets[name].split('/').pop(), link.href).href;
+			next.onload = () => link.remove();
+			link.after(next);
+		});
+	}
 	function connect() {
-		const es = new EventSource('/dev-reload');
+		const es = new EventSource('/dev-reload?since=' + encodeURIComponent(build));
+		es.addEventListener('hello', (e) => { build = JSON.parse(e.data).build; });
+		es.addEventListener('css-changed', (e) => swapStylesheets(JSON.parse(e.data).assets));
+		es.addEventListener('page-changed', (e) => {
+			if (JSON.parse(e.data).paths.includes(currentPage())) location.reload();
+		});
+		es.addEventListener('reload', () => location.reload());
 		es.onerror = () => {
 			es.close();
-			const interval = setInterval(async () => {
-				try {
-					await fetch('/dev-reload');
-					clearInterval(interval);
-					location.reload();
-				} catch (e) {}
-			}, 200);
+			setTimeout(connect, 200);
 		};
 	}
 	connect();
 })();
 </script>`
 
+// buildState describes the build this process serves and the events that bring
+// pages from the previous build up to date.
+type buildState struct {
+	ID        string
+	PrevID    string
+	Events    []internal.ReloadEvent
+	Inspector *internal.BuildInspector
+	Site      fs.FS
+}
+
+// buildRecord is the form of the last build saved in devBuildFile.
+type buildRecord struct {
+	ID      string                           `json:"id"`
+	Outputs map[string]internal.OutputDigest `json:"outputs"`
+}
+
 func main() {
-	if err := build(); err != nil {
+	writeDist := flag.Bool("write-dist", false, "also write each build to dist/ while serving it from memory")
+	themeDir := flag.String("theme", "", "directory whose templates, contents/ and static/ files replace the built-in theme's")
+	flag.Parse()
+
+	var out internal.Output = internal.NewMemoryOutput()
+	if *writeDist {
+		out = internal.NewWriteThroughOutput(internal.DiskOutput{})
+	}
+	theme := internal.Theme{Dir: *themeDir, Base: internal.DefaultTheme()}
+	state, err := build(out, theme)
+	if err != nil {
 		log.Fatalf("initial build failed: %v", err)
 	}
+	cfg, err := internal.LoadConfigFS(theme.Layer(configDir, "contents"))
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	// The inspector outlines entries of every collection; the editor writes posts
+	// to the first one, which is the blog unless collections say otherwise.
+	contentDirs := cfg.SourceDirs(blogDir)
+
+	static, err := internal.NewStaticHandler(state.Site, internal.ServeConfig{}, internal.AssetsConfig{})
+	if err != nil {
+		log.Fatalf("failed to load the build: %v", err)
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/dev-reload", sseHandler)
-	mux.HandleFunc("/", serveHandler)
+	mux.HandleFunc("/dev-reload", sseHandler(state))
+	mux.Handle("/__mehub/", state.Inspector.Handler("/__mehub/", contentDirs))
+	mux.Handle("/__mehub/editor/", (&internal.PostEditor{BlogDir: contentDirs[0]}).Handler("/__mehub/editor/"))
+	mux.HandleFunc("/", serveHandler(static, state))
 
-	log.Printf("dev server → http://localhost%s", port)
+	log.Printf("dev server → http://localhost%s (build details under /__mehub/, post editor at /__mehub/editor/)", port)
 	if err := http.ListenAndServe(port, mux); err != nil {
 		log.Fatal(err)
 	}
 }
 
-// sseHandler holds an SSE stream open. The browser reload is triggered
-// when the connection drops during a server restart.
-func sseHandler(w http.ResponseWriter, r *http.Request) {
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "streaming not supported", http.StatusInternalServerError)
-		return
-	}
+// sseHandler holds an SSE stream open. A page reconnecting with the ID of the
+// previous build receives that rebuild's events, one from an older build is told
+// to reload, and every page then learns the current build ID.
+func sseHandler(state *buildState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
 
-	fmt.Fprintf(w, "data: connected\n\n")
-	flusher.Flush()
+		var events []internal.ReloadEvent
+		switch since := r.URL.Query().Get("since"); {
+		case since == "" || since == state.ID:
+		case since == state.PrevID:
+			events = state.Events
+		default:
+			events = []internal.ReloadEvent{{Name: "reload", Data: struct{}{}}}
+		}
+		events = append(events, internal.ReloadEvent{Name: "hello", Data: map[string]string{"build": state.ID}})
+		for _, e := range events {
+			if _, err := e.WriteTo(w); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
 
-	for {
-		select {
-		case <-r.Context().Done():
-			return
-		case <-ticker.C:
-			fmt.Fprintf(w, "event: heartbeat\ndata: \n\n")
-			flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				fmt.Fprintf(w, "event: heartbeat\ndata: \n\n")
+				flusher.Flush()
+			}
 		}
 	}
 }
 
-// serveHandler serves files from dist/. HTML files have the live-reload script
-// injected before </body>. All other files are served directly.
-func serveHandler(w http.ResponseWriter, r *http.Request) {
-	p := filepath.Join("dist", filepath.Clean(r.URL.Path))
-
-	info, err := os.Stat(p)
-	if err != nil {
-		http.NotFound(w, r)
-		return
-	}
+// serveHandler serves the in-memory build, resolving clean URLs the same way as
+// `ssg serve`. HTML files, including the 404 page, have the live-reload script
+// injected before </body>. All other files are served directly, using their
+// precompressed variants when available.
+func serveHandler(static *internal.StaticHandler, state *buildState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := static.Redirect(r.URL.Path); ok {
+			internal.RedirectTo(w, r, target)
+			return
+		}
+		rel, ok := static.Resolve(r.URL.Path)
+		if !ok {
+			serveHTML(w, r, static.FS, static.NotFoundPage, http.StatusNotFound, state.ID)
+			return
+		}
 
-	if info.IsDir() {
-		p = filepath.Join(p, "index.html")
-	}
+		if strings.HasSuffix(rel, ".html") {
+			serveHTML(w, r, static.FS, rel, http.StatusOK, state.ID)
+			return
+		}
 
-	if strings.HasSuffix(p, ".html") {
-		serveHTML(w, r, p)
-		return
+		static.ServeFile(w, r, rel)
 	}
-
-	http.ServeFile(w, r, p)
 }
 
-// serveHTML reads an HTML file, injects the live-reload script, and writes it.
-func serveHTML(w http.ResponseWriter, r *http.Request, path string) {
-	content, err := os.ReadFile(path)
+// serveHTML reads an HTML file from site, injects the live-reload script for
+// buildID, and writes it with the given status.
+func serveHTML(w http.ResponseWriter, r *http.Request, site fs.FS, name string, status int, buildID string) {
+	content, err := fs.ReadFile(site, name)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	body := string(content) + liveReloadSnippet
+	snippet := strings.ReplaceAll(liveReloadScript, "__BUILD_ID__", buildID)
+	body := internal.InjectBeforeBodyEnd(string(content), snippet)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
 	fmt.Fprint(w, body)
 }
 
-// build runs the SSG pipeline then compiles Tailwind CSS.
-func build() error {
+// build runs the SSG pipeline into out with theme, including the pre- and post-build
+// hooks declared in the project config, and works out which outputs changed since
+// the last build.
+func build(out internal.Output, theme internal.Theme) (*buildState, error) {
 	start := time.Now()
 
-	if err := runAuditTags(); err != nil {
-		return fmt.Errorf("audit tags: %w", err)
+	state := &buildState{
+		ID:        strconv.FormatInt(start.UnixNano(), 36),
+		Inspector: internal.NewBuildInspector(),
+		Site:      out.FS("dist"),
+	}
+	var prev buildRecord
+	if data, err := os.ReadFile(devBuildFile); err == nil {
+		if err := json.Unmarshal(data, &prev); err != nil {
+			log.Printf("Warning: Failed to read %s: %v", devBuildFile, err)
+		}
+		state.PrevID = prev.ID
 	}
 
 	count, err := internal.RunPipeline(
 		"dist",
-		"internal/templates/contents",
+		configDir,
 		"internal/templates",
-		"blog",
+		blogDir,
 		"internal/templates/static",
+		internal.WithInspector(state.Inspector),
+		internal.WithOutput(out),
+		internal.WithTheme(theme),
+		internal.WithPagesDir("pages"),
+		internal.WithoutCompression(),
 	)
 	if err != nil {
-		return fmt.Errorf("ssg pipeline: %w", err)
+		return nil, fmt.Errorf("ssg pipeline: %w", err)
 	}
 
-	if err := runTailwind(); err != nil {
-		return fmt.Errorf("tailwind: %w", err)
-	}
-
-	log.Printf("✅ built %d posts in %v", count, time.Since(start))
-	return nil
-}
-
-// runAuditTags executes the Python tag audit script.
-func runAuditTags() error {
-	cmd := exec.Command("python3", "scripts/audit_tags.py")
-	out, err := cmd.CombinedOutput()
+	digests, err := internal.DigestOutputs(state.Site)
 	if err != nil {
-		return fmt.Errorf("%w\n%s", err, out)
+		return nil, fmt.Errorf("failed to digest outputs: %w", err)
 	}
-	return nil
-}
-
-// runTailwind compiles input.css into dist/styles.css.
-func runTailwind() error {
-	cmd := exec.Command(
-		"tailwindcss",
-		"-i", "internal/templates/input.css",
-		"-o", "dist/styles.css",
-		"--minify",
-	)
-	out, err := cmd.CombinedOutput()
+	if state.PrevID != "" {
+		changed := internal.ChangedDigests(prev.Outputs, digests)
+		state.Events = internal.ReloadEvents(changed)
+		log.Printf("%d outputs changed since the last build", len(changed))
+	}
+	record, err := json.Marshal(buildRecord{ID: state.ID, Outputs: digests})
 	if err != nil {
-		return fmt.Errorf("%w\n%s", err, out)
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(devBuildFile), 0755); err != nil {
+		return nil, err
 	}
-	return nil
+	if err := os.WriteFile(devBuildFile, record, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record build: %w", err)
+	}
+
+	log.Printf("✅ built %d posts in %v", count, time.Since(start))
+	return state, nil
 }
@@ -1,26 +1,199 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"mehub/internal"
 )
 
+const (
+	distDir      = "dist"
+	configDir    = "internal/templates/contents"
+	templatesDir = "internal/templates"
+	blogDir      = "blog"
+	pagesDir     = "pages"
+	publicDir    = "internal/templates/static"
+)
+
 func main() {
-	start := time.Now()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "localize-images":
+			localizeImages(os.Args[2:])
+			return
+		case "diff":
+			diff(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		}
+	}
+	build(os.Args[1:])
+}
+
+// themeFlag registers the -theme flag on fs.
+func themeFlag(fs *flag.FlagSet) *string {
+	return fs.String("theme", "", "directory whose templates, contents/ and static/ files replace the built-in theme's")
+}
 
-	distDir := "dist"
-	configDir := "internal/templates/contents"
-	templatesDir := "internal/templates"
-	blogDir := "blog"
-	publicDir := "internal/templates/static"
+// siteTheme layers dir over the site directories, which in turn fall back to the
+// theme embedded in the binary, so the SSG also works outside a repo checkout.
+func siteTheme(dir string) internal.Theme {
+	return internal.Theme{Dir: dir, Base: internal.DefaultTheme()}
+}
+
+// loadConfig reads the site config as the build sees it under theme.
+func loadConfig(theme internal.Theme) (*internal.SiteConfig, error) {
+	return internal.LoadConfigFS(theme.Layer(configDir, "contents"))
+}
+
+// build generates the full site into dist/.
+func build(args []string) {
+	fs := flag.NewFlagSet("ssg", flag.ExitOnError)
+	epoch := fs.Int64("source-date-epoch", -1, "build timestamp in Unix seconds for reproducible output (defaults to $SOURCE_DATE_EPOCH, then the current time)")
+	theme := themeFlag(fs)
+	fs.Parse(args)
+
+	opts := []internal.PipelineOption{internal.WithTheme(siteTheme(*theme)), internal.WithPagesDir(pagesDir)}
+	if *epoch >= 0 {
+		opts = append(opts, internal.WithBuildTime(time.Unix(*epoch, 0).UTC()))
+	}
 
-	count, err := internal.RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir)
+	start := time.Now()
+	count, err := internal.RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, opts...)
 	if err != nil {
 		log.Fatalf("Build failed: %v", err)
 	}
 
 	fmt.Printf("✅ Build completed: generated %d posts in %v\n", count, time.Since(start))
 }
+
+// localizeImages downloads remote images referenced by blog posts into the local store,
+// optionally rewriting the markdown to point at the local copies.
+func localizeImages(args []string) {
+	fs := flag.NewFlagSet("localize-images", flag.ExitOnError)
+	rewrite := fs.Bool("rewrite", false, "rewrite markdown to reference the localized copies")
+	theme := themeFlag(fs)
+	fs.Parse(args)
+
+	cfg, err := loadConfig(siteTheme(*theme))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	result, err := internal.LocalizePostImages(blogDir, cfg.RemoteImages, *rewrite)
+	if err != nil {
+		log.Fatalf("Localize failed: %v", err)
+	}
+	for _, failure := range result.Failed {
+		log.Printf("Warning: %s", failure)
+	}
+
+	fmt.Printf("✅ Localized %d images, rewrote %d posts\n", result.Images, result.Rewritten)
+	if len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// diff compares two builds and prints a Markdown report of the changed outputs. Each
+// argument is either an existing dist directory or a git revision to build.
+func diff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	maxLines := fs.Int("max-lines", 200, "maximum diff lines shown per file (0 for no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ssg diff [-max-lines n] <old dist|rev> <new dist|rev>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// Worktrees are removed explicitly because log.Fatalf skips deferred calls.
+	var cleanups []func()
+	fail := func(err error) {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+		log.Fatalf("Diff failed: %v", err)
+	}
+
+	// Both revisions share one build clock so timestamps do not show up as changes.
+	if os.Getenv("SOURCE_DATE_EPOCH") == "" {
+		os.Setenv("SOURCE_DATE_EPOCH", strconv.FormatInt(time.Now().Unix(), 10))
+	}
+
+	dirs := make([]string, 2)
+	for i, arg := range fs.Args() {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			dirs[i] = arg
+			continue
+		}
+		log.Printf("Building %s...", arg)
+		dir, cleanup, err := internal.BuildRevision(".", arg)
+		if err != nil {
+			fail(err)
+		}
+		cleanups = append(cleanups, cleanup)
+		dirs[i] = dir
+	}
+
+	result, err := internal.DiffTrees(dirs[0], dirs[1])
+	if err != nil {
+		fail(err)
+	}
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+	title := fmt.Sprintf("Site diff: `%s` → `%s`", fs.Arg(0), fs.Arg(1))
+	internal.WriteDiffMarkdown(os.Stdout, result, title, *maxLines)
+}
+
+// serve runs the production static server over a built dist directory until it
+// receives SIGINT or SIGTERM.
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dir := fs.String("dir", distDir, "directory to serve")
+	addr := fs.String("addr", "", "listen address (defaults to serve.addr in the config, then :8080)")
+	theme := themeFlag(fs)
+	fs.Parse(args)
+
+	var cfg internal.SiteConfig
+	if loaded, err := loadConfig(siteTheme(*theme)); err == nil {
+		cfg = *loaded
+	} else {
+		log.Printf("Warning: Failed to load config, using serve defaults: %v", err)
+	}
+	if *addr != "" {
+		cfg.Serve.Addr = *addr
+	}
+
+	handler, err := internal.NewStaticHandler(os.DirFS(*dir), cfg.Serve, cfg.Assets)
+	if err != nil {
+		log.Fatalf("Serve failed: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:              cfg.Serve.ServeAddr(),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving %s on %s", *dir, srv.Addr)
+	if err := internal.RunServer(ctx, srv, cfg.Serve.ShutdownTimeout); err != nil {
+		log.Fatalf("Serve failed: %v", err)
+	}
+}
@@ -17,6 +17,8 @@ Feature: Site Generation Pipeline
     And the output directory should contain "search-index.json"
     And the output directory should contain "llms.txt"
     And the output directory should contain "api/manifest.json"
+    And the output directory should contain "api/schema/v1/manifest.json"
+    And the output directory should contain "api/schema/v1/search-index.json"
     And the output directory should contain "blog"
     And the output directory should contain "tags"
 
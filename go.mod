@@ -3,11 +3,14 @@ module mehub
 go 1.26
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/cucumber/godog v0.16.0
 	github.com/tdewolff/minify/v2 v2.24.17
+	github.com/tdewolff/parse/v2 v2.8.16
 	github.com/yuin/goldmark v1.8.5
 	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
 	go.yaml.in/yaml/v4 v4.0.0-rc.6
+	golang.org/x/image v0.36.0
 )
 
 require (
@@ -20,5 +23,5 @@ require (
 	github.com/hashicorp/go-memdb v1.3.5 // indirect
 	github.com/hashicorp/golang-lru v0.5.4 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	github.com/tdewolff/parse/v2 v2.8.16 // indirect
+	golang.org/x/text v0.34.0 // indirect
 )
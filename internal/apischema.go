@@ -0,0 +1,309 @@
+package internal
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APISchemaVersion is the semantic version of the published API contracts. Bump the
+// major version whenever a field is removed, renamed, or changes type.
+const APISchemaVersion = "1.1.0"
+
+// committedSchemas holds the reviewed API contracts. Builds validate against these
+// rather than the Go types, so a struct change that breaks consumers fails the build
+// until the schema is regenerated with go test ./internal -run TestCommittedAPISchemas -update.
+//
+//go:embed apischema
+var committedSchemas embed.FS
+
+// JSONSchema is the subset of the JSON Schema (draft 2020-12) vocabulary needed to
+// describe and validate the generated API files.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Version              string                 `json:"version,omitempty"`
+	Type                 []string               `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// apiSchemas maps each published API output to the Go type that defines its shape.
+var apiSchemas = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"manifest", reflect.TypeFor[Manifest]()},
+	{"search-index", reflect.TypeFor[[]SearchItem]()},
+}
+
+// APISchemaMajor returns the major component of APISchemaVersion, used in schema paths.
+func APISchemaMajor() string {
+	major, _, _ := strings.Cut(APISchemaVersion, ".")
+	return "v" + major
+}
+
+// APISchemaPath returns the site-relative path of the published schema for name.
+func APISchemaPath(name string) string {
+	return "api/schema/" + APISchemaMajor() + "/" + name + ".json"
+}
+
+// APISchema loads the committed JSON Schema document for the named API output, with
+// its $id resolved against baseURL when one is given.
+func APISchema(name, baseURL string) (*JSONSchema, error) {
+	data, err := committedSchemas.ReadFile("apischema/" + APISchemaMajor() + "/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown API schema %q", name)
+	}
+	var schema JSONSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", name, err)
+	}
+	if baseURL != "" {
+		id, err := url.JoinPath(baseURL, APISchemaPath(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build schema URL for %s: %w", name, err)
+		}
+		schema.ID = id
+	}
+	return &schema, nil
+}
+
+// reflectAPISchema derives the JSON Schema for the named API output from its Go type.
+// It is only used to regenerate and check the committed schemas.
+func reflectAPISchema(name string) (*JSONSchema, error) {
+	for _, s := range apiSchemas {
+		if s.name == name {
+			defs := make(map[string]*JSONSchema)
+			var root *JSONSchema
+			if s.typ.Kind() == reflect.Struct {
+				root = structSchema(s.typ, defs) // inline the top-level document
+			} else {
+				root = schemaFor(s.typ, defs)
+			}
+			root.Schema = "https://json-schema.org/draft/2020-12/schema"
+			root.Title = name
+			root.Version = APISchemaVersion
+			if len(defs) > 0 {
+				root.Defs = defs
+			}
+			return root, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown API schema %q", name)
+}
+
+// schemaFor reflects t into a schema. Named struct types are registered once in defs
+// and referenced, so shared types like BlogItem appear as reusable definitions.
+func schemaFor(t reflect.Type, defs map[string]*JSONSchema) *JSONSchema {
+	if t == reflect.TypeFor[time.Time]() {
+		return &JSONSchema{Type: []string{"string"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaFor(t.Elem(), defs)
+		if s.Ref != "" {
+			return &JSONSchema{Ref: s.Ref}
+		}
+		s.Type = append(s.Type, "null")
+		return s
+	case reflect.String:
+		return &JSONSchema{Type: []string{"string"}}
+	case reflect.Bool:
+		return &JSONSchema{Type: []string{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: []string{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: []string{"number"}}
+	case reflect.Slice, reflect.Array:
+		// Nil slices marshal to null, so arrays are nullable.
+		return &JSONSchema{Type: []string{"array", "null"}, Items: schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return &JSONSchema{Type: []string{"object"}}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, defs)
+		}
+		ref := "#/$defs/" + t.Name()
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // reserve before recursing to stop cycles
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return &JSONSchema{Ref: ref}
+	}
+	return &JSONSchema{}
+}
+
+// structSchema describes a struct's exported JSON fields. Fields without omitempty are
+// always emitted, so they are listed as required. Objects stay open to additional
+// properties because adding a field is a minor, non-breaking version change.
+func structSchema(t reflect.Type, defs map[string]*JSONSchema) *JSONSchema {
+	s := &JSONSchema{
+		Type:       []string{"object"},
+		Properties: make(map[string]*JSONSchema),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = schemaFor(f.Type, defs)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// ValidateJSON checks that data conforms to schema, returning the first violation found.
+func ValidateJSON(schema *JSONSchema, data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return validateValue(schema, schema, v, "$")
+}
+
+func validateValue(root, s *JSONSchema, v interface{}, path string) error {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/$defs/")
+		def, ok := root.Defs[name]
+		if !ok {
+			return fmt.Errorf("%s: unresolved reference %s", path, s.Ref)
+		}
+		return validateValue(root, def, v, path)
+	}
+
+	if len(s.Type) > 0 && !matchesType(s.Type, v) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), jsonTypeOf(v))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, req := range s.Required {
+			if _, ok := val[req]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, req)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+				continue
+			}
+			if err := validateValue(root, prop, val[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				if err := validateValue(root, s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				return fmt.Errorf("%s: %q is not a valid date-time", path, val)
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(types []string, v interface{}) bool {
+	actual := jsonTypeOf(v)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeOf(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == float64(int64(val)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// validateAPIOutput validates marshalled API output against its published schema.
+func (g *SiteGenerator) validateAPIOutput(name string, data []byte) error {
+	schema, err := APISchema(name, g.Config.Landing.URL)
+	if err != nil {
+		return err
+	}
+	if err := ValidateJSON(schema, data); err != nil {
+		return fmt.Errorf("%s does not match schema %s: %w", name, APISchemaVersion, err)
+	}
+	return nil
+}
+
+// GenerateAPISchemas publishes the JSON Schema of every API output under api/schema/.
+func (g *SiteGenerator) GenerateAPISchemas(distDir string) error {
+	for _, s := range apiSchemas {
+		schema, err := APISchema(s.name, g.Config.Landing.URL)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(distDir, filepath.FromSlash(APISchemaPath(s.name)))
+		jsonData, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema %s: %w", s.name, err)
+		}
+		if err := g.Output.WriteFile(path, jsonData); err != nil {
+			return fmt.Errorf("failed to write schema %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
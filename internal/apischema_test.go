@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPISchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		validate func(*testing.T, *JSONSchema)
+		wantErr  bool
+	}{
+		{
+			name:   "Manifest References Shared Definitions",
+			schema: "manifest",
+			validate: func(t *testing.T, s *JSONSchema) {
+				if s.ID != "http://example.com/api/schema/v1/manifest.json" {
+					t.Errorf("Unexpected $id %q", s.ID)
+				}
+				if s.Version != APISchemaVersion {
+					t.Errorf("Expected version %s, got %s", APISchemaVersion, s.Version)
+				}
+				if s.Properties["blog"].Ref != "#/$defs/BlogRegistry" {
+					t.Errorf("Expected blog to reference BlogRegistry, got %+v", s.Properties["blog"])
+				}
+				blogItem, ok := s.Defs["BlogItem"]
+				if !ok {
+					t.Fatal("Expected BlogItem definition")
+				}
+				if _, ok := blogItem.Properties["skills"]; !ok {
+					t.Error("Expected BlogItem tags to be published as 'skills'")
+				}
+			},
+		},
+		{
+			name:   "Search Index Is An Array",
+			schema: "search-index",
+			validate: func(t *testing.T, s *JSONSchema) {
+				if s.Items == nil || s.Items.Ref != "#/$defs/SearchItem" {
+					t.Errorf("Expected array of SearchItem, got %+v", s.Items)
+				}
+			},
+		},
+		{
+			name:    "Unknown Schema",
+			schema:  "nope",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := APISchema(tt.schema, "http://example.com/")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("APISchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.validate != nil {
+				tt.validate(t, s)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema, err := APISchema("search-index", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "Valid Items",
+			data: `[{"title":"T","slug":"s","description":"d","date":"x","tags":["go"]}]`,
+		},
+		{
+			name: "Null Tags Allowed",
+			data: `[{"title":"T","slug":"s","description":"d","date":"x","tags":null}]`,
+		},
+		{
+			name:    "Missing Required Property",
+			data:    `[{"title":"T","description":"d","date":"x","tags":[]}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Wrong Type",
+			data:    `[{"title":1,"slug":"s","description":"d","date":"x","tags":[]}]`,
+			wantErr: true,
+		},
+		{
+			name: "Additional Property Allowed",
+			data: `[{"title":"T","slug":"s","description":"d","date":"x","tags":[],"extra":true}]`,
+		},
+		{
+			name:    "Invalid JSON",
+			data:    `[`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(schema, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPISchemaID(t *testing.T) {
+	for _, base := range []string{"https://example.com", "https://example.com/", "https://example.com/site/"} {
+		s, err := APISchema("manifest", base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.TrimSuffix(base, "/") + "/api/schema/v1/manifest.json"
+		if s.ID != want {
+			t.Errorf("APISchema(%q) $id = %q, want %q", base, s.ID, want)
+		}
+	}
+}
+
+// TestCommittedAPISchemas fails when the Go types drift from the committed contracts.
+// Review the diff and bump APISchemaVersion before regenerating with -update.
+func TestCommittedAPISchemas(t *testing.T) {
+	for _, s := range apiSchemas {
+		t.Run(s.name, func(t *testing.T) {
+			reflected, err := reflectAPISchema(s.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := json.MarshalIndent(reflected, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, '\n')
+
+			path := filepath.Join("apischema", APISchemaMajor(), s.name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, want, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Missing committed schema (run with -update): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s no longer matches %s; review the contract change and run with -update", path, s.name)
+			}
+		})
+	}
+}
+
+func TestGenerateAPISchemas(t *testing.T) {
+	gen := New(createConfig(), "")
+	distDir := t.TempDir()
+
+	if err := gen.GenerateAPISchemas(distDir); err != nil {
+		t.Fatalf("GenerateAPISchemas() error = %v", err)
+	}
+
+	for _, name := range []string{"manifest", "search-index"} {
+		content, err := os.ReadFile(filepath.Join(distDir, "api", "schema", "v1", name+".json"))
+		if err != nil {
+			t.Fatalf("Schema %s not written: %v", name, err)
+		}
+		var s JSONSchema
+		if err := json.Unmarshal(content, &s); err != nil {
+			t.Fatalf("Schema %s is not valid JSON: %v", name, err)
+		}
+		if s.Version != APISchemaVersion {
+			t.Errorf("Schema %s has version %q", name, s.Version)
+		}
+	}
+}
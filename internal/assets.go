@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// assetHashLength is the number of hex characters of the content hash kept in names.
+const assetHashLength = 8
+
+// defaultAssetManifest is where the asset manifest is written when none is configured.
+const defaultAssetManifest = "asset-manifest.json"
+
+// defaultAssetExcludes are files crawlers expect at fixed paths.
+var defaultAssetExcludes = []string{"robots.txt"}
+
+// AssetManifest maps site-relative asset paths to their fingerprinted names.
+type AssetManifest struct {
+	Assets map[string]string
+}
+
+// NewAssetManifest returns an empty manifest.
+func NewAssetManifest() *AssetManifest {
+	return &AssetManifest{Assets: make(map[string]string)}
+}
+
+// fingerprintName inserts the content hash of data before the extension of rel,
+// turning "styles.css" into "styles.3fa9c1d2.css".
+func fingerprintName(rel string, data []byte) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:assetHashLength]
+	ext := path.Ext(rel)
+	return strings.TrimSuffix(rel, ext) + "." + hash + ext
+}
+
+// Add records the fingerprinted name of the asset rel with the given content and
+// returns it.
+func (m *AssetManifest) Add(rel string, data []byte) string {
+	hashed := fingerprintName(rel, data)
+	m.Assets[rel] = hashed
+	return hashed
+}
+
+// Path returns the fingerprinted name of rel, or rel itself when the asset was not
+// fingerprinted. It is safe to call on a nil manifest.
+func (m *AssetManifest) Path(rel string) string {
+	if m == nil {
+		return rel
+	}
+	if hashed, ok := m.Assets[rel]; ok {
+		return hashed
+	}
+	return rel
+}
+
+// Write saves the manifest as JSON to path in out.
+func (m *AssetManifest) Write(out Output, path string) error {
+	data, err := json.MarshalIndent(m.Assets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset manifest: %w", err)
+	}
+	if err := out.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write asset manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadAssetManifest reads a manifest written by Write from name in fsys.
+func LoadAssetManifest(fsys fs.FS, name string) (*AssetManifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	m := NewAssetManifest()
+	if err := json.Unmarshal(data, &m.Assets); err != nil {
+		return nil, fmt.Errorf("failed to parse asset manifest %s: %w", name, err)
+	}
+	return m, nil
+}
+
+// excludedAsset reports whether rel matches one of the exclude patterns, either as a
+// full relative path or by its base name.
+func excludedAsset(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FingerprintAssets renames every non-HTML file already in distDir to its
+// content-hashed name and returns the resulting manifest. Files matching
+// cfg.Exclude or the default excludes keep their names.
+func FingerprintAssets(out Output, distDir string, cfg AssetsConfig) (*AssetManifest, error) {
+	manifest := NewAssetManifest()
+	excludes := append(append([]string{}, defaultAssetExcludes...), cfg.Exclude...)
+
+	var files []string
+	err := out.Walk(distDir, func(p string) error {
+		rel, err := filepath.Rel(distDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasSuffix(rel, ".html") || excludedAsset(rel, excludes) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan assets: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, rel := range files {
+		src := filepath.Join(distDir, filepath.FromSlash(rel))
+		data, err := out.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset %s: %w", rel, err)
+		}
+		hashed := manifest.Add(rel, data)
+		if err := out.Rename(src, filepath.Join(distDir, filepath.FromSlash(hashed))); err != nil {
+			return nil, fmt.Errorf("failed to fingerprint %s: %w", rel, err)
+		}
+	}
+	return manifest, nil
+}
+
+// asset resolves a site-relative asset path to its fingerprinted name for templates.
+func (g *SiteGenerator) asset(name string) string {
+	return g.Assets.Path(name)
+}
+
+// writeAsset minifies a generated asset and writes it into distDir, fingerprinting its
+// name when asset fingerprinting is enabled, and returns the name it was written under.
+func (g *SiteGenerator) writeAsset(distDir, rel string, data []byte) (string, error) {
+	if t, ok := minifyTypeFor(rel); ok {
+		minified, err := g.minifyBytes(t.name, data)
+		if err != nil {
+			return "", fmt.Errorf("failed to minify %s: %w", rel, err)
+		}
+		data = minified
+	}
+	name := rel
+	if g.Assets != nil {
+		name = g.Assets.Add(rel, data)
+	}
+	if err := g.Output.WriteFile(filepath.Join(distDir, filepath.FromSlash(name)), data); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	g.minified[name] = true
+	return name, nil
+}
+
+// GenerateAssetManifest writes the asset manifest when fingerprinting is enabled.
+func (g *SiteGenerator) GenerateAssetManifest(distDir string) error {
+	if g.Assets == nil {
+		return nil
+	}
+	name := g.Config.Assets.Manifest
+	if name == "" {
+		name = defaultAssetManifest
+	}
+	return g.Assets.Write(g.Output, filepath.Join(distDir, name))
+}
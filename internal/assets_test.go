@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintName(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want string
+	}{
+		{"styles.css", `^styles\.[0-9a-f]{8}\.css$`},
+		{"socials/github.svg", `^socials/github\.[0-9a-f]{8}\.svg$`},
+		{"LICENSE", `^LICENSE\.[0-9a-f]{8}$`},
+	}
+	for _, tt := range tests {
+		got := fingerprintName(tt.rel, []byte("body"))
+		if !regexp.MustCompile(tt.want).MatchString(got) {
+			t.Errorf("fingerprintName(%q) = %q, want match for %s", tt.rel, got, tt.want)
+		}
+	}
+
+	if fingerprintName("a.css", []byte("one")) == fingerprintName("a.css", []byte("two")) {
+		t.Error("Expected different content to produce different names")
+	}
+}
+
+func TestAssetManifestPath(t *testing.T) {
+	var nilManifest *AssetManifest
+	if got := nilManifest.Path("styles.css"); got != "styles.css" {
+		t.Errorf("Expected nil manifest to pass names through, got %q", got)
+	}
+
+	m := NewAssetManifest()
+	hashed := m.Add("styles.css", []byte("body{}"))
+	if got := m.Path("styles.css"); got != hashed {
+		t.Errorf("Expected %q, got %q", hashed, got)
+	}
+	if got := m.Path("unknown.js"); got != "unknown.js" {
+		t.Errorf("Expected unknown asset to pass through, got %q", got)
+	}
+}
+
+func TestFingerprintAssets(t *testing.T) {
+	distDir := t.TempDir()
+	files := map[string]string{
+		"styles.css":         "body{}",
+		"favicon.svg":        "<svg/>",
+		"socials/github.svg": "<svg/>",
+		"robots.txt":         "User-agent: *",
+		"avatar.png":         "png",
+		"index.html":         "<html></html>",
+	}
+	for name, content := range files {
+		p := filepath.Join(distDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := FingerprintAssets(DiskOutput{}, distDir, AssetsConfig{Exclude: []string{"avatar.png"}})
+	if err != nil {
+		t.Fatalf("FingerprintAssets() error = %v", err)
+	}
+
+	for _, name := range []string{"styles.css", "favicon.svg", "socials/github.svg"} {
+		hashed := m.Path(name)
+		if hashed == name {
+			t.Errorf("Expected %s to be fingerprinted", name)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(hashed))); err != nil {
+			t.Errorf("Fingerprinted file %s missing: %v", hashed, err)
+		}
+		if _, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected original %s to be renamed", name)
+		}
+	}
+
+	for _, name := range []string{"robots.txt", "avatar.png", "index.html"} {
+		if m.Path(name) != name {
+			t.Errorf("Expected %s to keep its name", name)
+		}
+		if _, err := os.Stat(filepath.Join(distDir, name)); err != nil {
+			t.Errorf("Expected %s to remain: %v", name, err)
+		}
+	}
+}
+
+func TestBuildFingerprintedAssets(t *testing.T) {
+	tmpDir := t.TempDir()
+	templatesDir := filepath.Join(tmpDir, "templates")
+	distDir := filepath.Join(tmpDir, "dist")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(distDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(distDir, "styles.css"), []byte("body{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	baseHTML := `{{ define "base.html" }}<link href="{{ asset "styles.css" }}">{{ template "content" . }}{{ end }}`
+	pageHTML := `{{ define "content" }}<script>fetch('{{ asset "search-index.json" }}')</script>{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "base.html"), []byte(baseHTML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"index.html", "work.html", "about.html", "404.html", "tags.html", "archive.html", "blog.html", "post.html"} {
+		if err := os.WriteFile(filepath.Join(templatesDir, f), []byte(pageHTML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &SiteConfig{Assets: AssetsConfig{Fingerprint: true}}
+	g := New(cfg, templatesDir)
+	var err error
+	if g.Assets, err = FingerprintAssets(DiskOutput{}, distDir, cfg.Assets); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Build(distDir, ProcessPosts(nil)); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(distDir, "asset-manifest.json"))
+	if err != nil {
+		t.Fatalf("Asset manifest missing: %v", err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	searchIndex, ok := manifest["search-index.json"]
+	if !ok {
+		t.Fatalf("Expected search index in manifest, got %v", manifest)
+	}
+
+	page, err := os.ReadFile(filepath.Join(distDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{manifest["styles.css"], searchIndex} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected page to reference %s, got %s", want, page)
+		}
+	}
+}
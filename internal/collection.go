@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultCollection returns the blog as it is built when the config declares no
+// collections: posts at blog/<slug>.html listed ten to a page from blog.html, with tag
+// pages, and included in the feed, search index and API manifest.
+func DefaultCollection() CollectionConfig {
+	return CollectionConfig{
+		Name:         "blog",
+		Title:        "Blog",
+		URL:          "blog/{slug}.html",
+		Template:     "post.html",
+		ListURL:      "blog.html",
+		ListTemplate: "blog.html",
+		TagURL:       "tags/{tag}.html",
+		Sort:         "date",
+		PageSize:     10,
+		Feed:         true,
+		Search:       true,
+		Manifest:     true,
+	}
+}
+
+// ContentCollections returns the configured collections with their defaults filled in,
+// or the default blog when none are configured.
+func (c *SiteConfig) ContentCollections() []CollectionConfig {
+	if len(c.Collections) == 0 {
+		return []CollectionConfig{DefaultCollection()}
+	}
+	collections := make([]CollectionConfig, len(c.Collections))
+	for i, cc := range c.Collections {
+		collections[i] = cc.withDefaults()
+	}
+	return collections
+}
+
+// withDefaults fills in the title, entry URL, templates, listing URL and sort order a
+// collection leaves out, all derived from its name.
+func (c CollectionConfig) withDefaults() CollectionConfig {
+	if c.Title == "" && c.Name != "" {
+		r, size := utf8.DecodeRuneInString(c.Name)
+		c.Title = string(unicode.ToUpper(r)) + c.Name[size:]
+	}
+	if c.URL == "" {
+		c.URL = c.Name + "/{slug}.html"
+	}
+	if c.Template == "" {
+		c.Template = "post.html"
+	}
+	if c.ListURL == "" {
+		c.ListURL = c.Name + ".html"
+	}
+	if c.Sort == "" {
+		c.Sort = "date"
+	}
+	return c
+}
+
+// validateCollections reports the first collection whose name is missing or taken,
+// whose URL patterns or sort order cannot be used, or whose pages could land on the
+// paths of another collection.
+func (c *SiteConfig) validateCollections() error {
+	seen := make(map[string]bool)
+	collections := c.ContentCollections()
+	for i, cc := range collections {
+		if cc.Name == "" {
+			return fmt.Errorf("collection with dir %q has no name", cc.Dir)
+		}
+		if seen[cc.Name] {
+			return fmt.Errorf("duplicate collection %q", cc.Name)
+		}
+		seen[cc.Name] = true
+
+		switch {
+		case !strings.Contains(cc.URL, "{slug}") || path.Ext(cc.URL) != ".html":
+			return fmt.Errorf("invalid collection %q: url %q must contain {slug} and end in .html", cc.Name, cc.URL)
+		case cc.TagURL != "" && (!strings.Contains(cc.TagURL, "{tag}") || path.Ext(cc.TagURL) != ".html"):
+			return fmt.Errorf("invalid collection %q: tagURL %q must contain {tag} and end in .html", cc.Name, cc.TagURL)
+		case cc.TagURL != "" && cc.ListTemplate == "":
+			return fmt.Errorf("invalid collection %q: tag pages need a listTemplate", cc.Name)
+		case path.Ext(cc.ListURL) != ".html":
+			return fmt.Errorf("invalid collection %q: listURL %q must end in .html", cc.Name, cc.ListURL)
+		case cc.PageSize < 0:
+			return fmt.Errorf("invalid collection %q: pageSize must not be negative", cc.Name)
+		}
+		if _, ok := postOrders[cc.Sort]; !ok {
+			return fmt.Errorf("invalid collection %q: unknown sort %q, expected date, date-asc or title", cc.Name, cc.Sort)
+		}
+
+		for _, other := range collections[:i] {
+			for _, p := range cc.pathPatterns() {
+				for _, q := range other.pathPatterns() {
+					if patternsOverlap(p.pattern, q.pattern) {
+						return fmt.Errorf("invalid collection %q: %s %q conflicts with the %s %q of collection %q", cc.Name, p.field, p.value, q.field, q.value, other.Name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pathPattern is a page path of a collection with its placeholders replaced by *.
+type pathPattern struct {
+	field, value, pattern string
+}
+
+// pathPatterns lists the paths the pages of the collection are rendered at: entries,
+// and when it has a list template, listing pages and tag pages.
+func (c CollectionConfig) pathPatterns() []pathPattern {
+	patterns := []pathPattern{{"url", c.URL, strings.ReplaceAll(c.URL, "{slug}", "*")}}
+	if c.ListTemplate != "" {
+		patterns = append(patterns,
+			pathPattern{"listURL", c.ListURL, c.ListURL},
+			pathPattern{"listURL", c.ListURL, strings.TrimSuffix(c.ListURL, ".html") + "/*.html"},
+		)
+		if c.TagURL != "" {
+			patterns = append(patterns, pathPattern{"tagURL", c.TagURL, strings.ReplaceAll(c.TagURL, "{tag}", "*")})
+		}
+	}
+	return patterns
+}
+
+// patternsOverlap reports whether some path matches both a and b, where * matches any
+// run of characters within one path segment.
+func patternsOverlap(a, b string) bool {
+	memo := make(map[[2]int]bool)
+	var overlap func(i, j int) bool
+	overlap = func(i, j int) bool {
+		key := [2]int{i, j}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+		var v bool
+		switch {
+		case i == len(a) || j == len(b):
+			v = strings.Trim(a[i:], "*") == "" && strings.Trim(b[j:], "*") == ""
+		case a[i] == '*':
+			v = overlap(i+1, j) || (b[j] != '/' && overlap(i, j+1))
+		case b[j] == '*':
+			v = overlap(i, j+1) || (a[i] != '/' && overlap(i+1, j))
+		default:
+			v = a[i] == b[j] && overlap(i+1, j+1)
+		}
+		memo[key] = v
+		return v
+	}
+	return overlap(0, 0)
+}
+
+// sourceDir returns the directory the entries of the collection are read from: Dir
+// when set, blogDir for the implicit default blog, and otherwise a sibling of blogDir
+// named after the collection.
+func (c CollectionConfig) sourceDir(blogDir string, implicit bool) string {
+	switch {
+	case c.Dir != "":
+		return c.Dir
+	case implicit:
+		return blogDir
+	default:
+		return filepath.Join(filepath.Dir(blogDir), c.Name)
+	}
+}
+
+// SourceDirs returns the directory each of ContentCollections is read from, in the
+// same order, with blogDir standing for the default blog as in RunPipeline.
+func (c *SiteConfig) SourceDirs(blogDir string) []string {
+	collections := c.ContentCollections()
+	dirs := make([]string, len(collections))
+	for i, col := range collections {
+		dirs[i] = col.sourceDir(blogDir, len(c.Collections) == 0)
+	}
+	return dirs
+}
+
+// EntryPath returns the site-relative path of the entry with slug.
+func (c CollectionConfig) EntryPath(slug string) string {
+	return strings.ReplaceAll(c.URL, "{slug}", slug)
+}
+
+// ListPath returns the site-relative path of listing page n, counting from one.
+func (c CollectionConfig) ListPath(n int) string {
+	if n <= 1 {
+		return c.ListURL
+	}
+	return strings.TrimSuffix(c.ListURL, ".html") + "/" + strconv.Itoa(n) + ".html"
+}
+
+// TagPath returns the site-relative path of the page listing the entries tagged tag.
+func (c CollectionConfig) TagPath(tag string) string {
+	return strings.ReplaceAll(c.TagURL, "{tag}", tag)
+}
+
+// owns reports whether the page at pagePath lies in a directory holding the entries,
+// later listing pages or tag pages of the collection.
+func (c CollectionConfig) owns(pagePath string) bool {
+	dirs := []string{path.Dir(c.URL), path.Dir(c.ListPath(2))}
+	if c.TagURL != "" {
+		dirs = append(dirs, path.Dir(c.TagURL))
+	}
+	for _, dir := range dirs {
+		if dir != "." && strings.HasPrefix(pagePath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// postOrders maps each collection sort order to whether post a comes before post b.
+// Ties fall back to slug order so repeated builds list entries identically.
+var postOrders = map[string]func(a, b Post) bool{
+	"date": func(a, b Post) bool {
+		if !a.Date.Equal(b.Date) {
+			return a.Date.After(b.Date)
+		}
+		return a.Slug < b.Slug
+	},
+	"date-asc": func(a, b Post) bool {
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Slug < b.Slug
+	},
+	"title": func(a, b Post) bool {
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.Slug < b.Slug
+	},
+}
+
+// ProcessCollection sorts the entries of collection c in its configured order and
+// groups them like ProcessPosts. The result lists c as its only collection.
+func ProcessCollection(c CollectionConfig, posts []Post) *ContentData {
+	if less, ok := postOrders[c.Sort]; ok {
+		sort.SliceStable(posts, func(i, j int) bool { return less(posts[i], posts[j]) })
+	}
+	return processCollection(c, posts)
+}
+
+// collections returns the collections of data. Content processed without one, such
+// as a ContentData built by hand, is treated as the first configured collection.
+func (g *SiteGenerator) collections(data *ContentData) []Collection {
+	if len(data.Collections) > 0 {
+		return data.Collections
+	}
+	return []Collection{{CollectionConfig: g.Config.ContentCollections()[0], Content: data}}
+}
+
+// sortedEntries returns the entries of every collection for which include is true,
+// newest first.
+func sortedEntries(collections []Collection, include func(Collection) bool) []collectionEntry {
+	var entries []collectionEntry
+	for _, c := range collections {
+		if !include(c) {
+			continue
+		}
+		for _, post := range c.Content.Posts {
+			entries = append(entries, collectionEntry{Post: post, Path: c.EntryPath(post.Slug), Collection: c.Name})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return postOrders["date"](entries[i].Post, entries[j].Post) })
+	return entries
+}
+
+// collectionEntry is a post together with the collection it belongs to and its path.
+type collectionEntry struct {
+	Post       Post
+	Path       string
+	Collection string
+}
@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContentCollections(t *testing.T) {
+	cfg := &SiteConfig{}
+	if got := cfg.ContentCollections(); !reflect.DeepEqual(got, []CollectionConfig{DefaultCollection()}) {
+		t.Errorf("ContentCollections() = %+v, want the default blog", got)
+	}
+
+	cfg.Collections = []CollectionConfig{{Name: "notes", Dir: "contents/notes"}}
+	want := CollectionConfig{
+		Name:     "notes",
+		Title:    "Notes",
+		Dir:      "contents/notes",
+		URL:      "notes/{slug}.html",
+		Template: "post.html",
+		ListURL:  "notes.html",
+		Sort:     "date",
+	}
+	if got := cfg.ContentCollections(); !reflect.DeepEqual(got, []CollectionConfig{want}) {
+		t.Errorf("ContentCollections() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateCollections(t *testing.T) {
+	tests := []struct {
+		name        string
+		collections []CollectionConfig
+		wantErr     string
+	}{
+		{"Default Blog", nil, ""},
+		{"Configured", []CollectionConfig{{Name: "blog"}, {Name: "talks", URL: "talks/{slug}/index.html", Sort: "date-asc"}}, ""},
+		{"Missing Name", []CollectionConfig{{Dir: "notes"}}, "has no name"},
+		{"Duplicate Name", []CollectionConfig{{Name: "notes"}, {Name: "notes", URL: "n/{slug}.html"}}, `duplicate collection "notes"`},
+		{"URL Without Slug", []CollectionConfig{{Name: "notes", URL: "notes/index.html"}}, "must contain {slug}"},
+		{"Tag URL Without Tag", []CollectionConfig{{Name: "notes", ListTemplate: "blog.html", TagURL: "notes/tags.html"}}, "must contain {tag}"},
+		{"Tag Pages Without Listing", []CollectionConfig{{Name: "notes", TagURL: "notes/tags/{tag}.html"}}, "need a listTemplate"},
+		{"List URL Not HTML", []CollectionConfig{{Name: "notes", ListURL: "notes/"}}, "must end in .html"},
+		{"Negative Page Size", []CollectionConfig{{Name: "notes", PageSize: -1}}, "must not be negative"},
+		{"Unknown Sort", []CollectionConfig{{Name: "notes", Sort: "random"}}, `unknown sort "random"`},
+		{"Overlapping Entries", []CollectionConfig{{Name: "notes", URL: "posts/{slug}.html"}, {Name: "talks", URL: "posts/talk-{slug}.html"}}, `url "posts/talk-{slug}.html" conflicts with the url "posts/{slug}.html" of collection "notes"`},
+		{"Listing Over Entries", []CollectionConfig{{Name: "blog"}, {Name: "archive", ListURL: "blog/all.html", ListTemplate: "blog.html"}}, `listURL "blog/all.html" conflicts with the url "blog/{slug}.html" of collection "blog"`},
+		{"Shared Tag Pages", []CollectionConfig{{Name: "blog", ListTemplate: "blog.html", TagURL: "tags/{tag}.html"}, {Name: "notes", ListTemplate: "blog.html", TagURL: "tags/{tag}.html"}}, `tagURL "tags/{tag}.html" conflicts`},
+		{"Nested Entries Apart", []CollectionConfig{{Name: "blog", ListTemplate: "blog.html"}, {Name: "notes", URL: "blog/notes/{slug}.html"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &SiteConfig{Collections: tt.collections}
+			err := cfg.validateCollections()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateCollections() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateCollections() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCollectionSourceDir(t *testing.T) {
+	blogDir := filepath.Join("site", "blog")
+	tests := []struct {
+		name       string
+		collection CollectionConfig
+		implicit   bool
+		want       string
+	}{
+		{"Implicit Blog", DefaultCollection(), true, blogDir},
+		{"Configured Blog", CollectionConfig{Name: "blog"}, false, blogDir},
+		{"Named After Collection", CollectionConfig{Name: "notes"}, false, filepath.Join("site", "notes")},
+		{"Explicit Dir", CollectionConfig{Name: "notes", Dir: "content/notes"}, false, "content/notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.collection.sourceDir(blogDir, tt.implicit); got != tt.want {
+				t.Errorf("sourceDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSiteConfigSourceDirs(t *testing.T) {
+	blogDir := filepath.Join("site", "blog")
+	implicit := &SiteConfig{}
+	if got := implicit.SourceDirs(blogDir); !reflect.DeepEqual(got, []string{blogDir}) {
+		t.Errorf("SourceDirs() = %q, want the blog dir", got)
+	}
+	configured := &SiteConfig{Collections: []CollectionConfig{{Name: "posts"}, {Name: "notes", Dir: "content/notes"}}}
+	want := []string{filepath.Join("site", "posts"), "content/notes"}
+	if got := configured.SourceDirs(blogDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceDirs() = %q, want %q", got, want)
+	}
+}
+
+func TestCollectionPaths(t *testing.T) {
+	c := DefaultCollection()
+	tests := []struct {
+		got, want string
+	}{
+		{c.EntryPath("hello"), "blog/hello.html"},
+		{c.ListPath(1), "blog.html"},
+		{c.ListPath(3), "blog/3.html"},
+		{c.TagPath("go"), "tags/go.html"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+
+	for _, p := range []string{"blog/hello.html", "blog/2.html", "tags/go.html"} {
+		if !c.owns(p) {
+			t.Errorf("Expected the blog to own %s", p)
+		}
+	}
+	for _, p := range []string{"blog.html", "about.html", "notes/hello.html"} {
+		if c.owns(p) {
+			t.Errorf("Expected the blog not to own %s", p)
+		}
+	}
+}
+
+func TestProcessCollection(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC) }
+	posts := func() []Post {
+		return []Post{
+			{Frontmatter: Frontmatter{Title: "Beta", Date: day(2)}, Slug: "b"},
+			{Frontmatter: Frontmatter{Title: "Alpha", Date: day(3)}, Slug: "a"},
+			{Frontmatter: Frontmatter{Title: "Gamma", Date: day(1)}, Slug: "c"},
+		}
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"date", []string{"a", "b", "c"}},
+		{"date-asc", []string{"c", "b", "a"}},
+		{"title", []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			c := CollectionConfig{Name: "notes", Sort: tt.sort}.withDefaults()
+			data := ProcessCollection(c, posts())
+			var slugs []string
+			for _, p := range data.Posts {
+				slugs = append(slugs, p.Slug)
+				if p.Collection != "notes" || p.Path != "notes/"+p.Slug+".html" {
+					t.Errorf("Expected %s to belong to notes, got %q at %q", p.Slug, p.Collection, p.Path)
+				}
+			}
+			if !reflect.DeepEqual(slugs, tt.want) {
+				t.Errorf("ProcessCollection() order = %v, want %v", slugs, tt.want)
+			}
+			if len(data.Collections) != 1 || data.Collections[0].Content != data {
+				t.Errorf("Expected the result to list its collection, got %+v", data.Collections)
+			}
+		})
+	}
+}
+
+func TestRunPipelineWithCollections(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	collectionsYAML := `
+collections:
+  - name: blog
+    listTemplate: blog.html
+    tagURL: "tags/{tag}.html"
+    pageSize: 10
+    feed: true
+    search: true
+    manifest: true
+  - name: notes
+    title: Field Notes
+    url: "notes/{slug}/index.html"
+    listTemplate: blog.html
+    pageSize: 1
+    sort: title
+    search: true
+`
+	config, err := os.ReadFile(filepath.Join(configDir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), append(config, collectionsYAML...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	listHTML := `{{ define "content" }}<h1>{{ .Collection.Title }}</h1>{{ range .Posts }}<a href="{{ relURL .Path }}">{{ .Title }}</a>{{ end }}{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "blog.html"), []byte(listHTML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notesDir := filepath.Join(filepath.Dir(blogDir), "notes")
+	for name, title := range map[string]string{"zebra.md": "Zebra", "aardvark.md": "Aardvark"} {
+		note := "---\ntitle: " + title + "\ndate: 2026-07-01T00:00:00Z\ndescription: A note\n---\nNoted."
+		if err := os.MkdirAll(notesDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(notesDir, name), []byte(note), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := NewMemoryOutput()
+	count, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithOutput(out))
+	if err != nil {
+		t.Fatalf("RunPipeline failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 entries, got %d", count)
+	}
+
+	site := out.FS(distDir)
+	checks := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{"blog.html", []string{"<h1>Blog</h1>", "<a href=blog/test.html>Integration Post</a>"}, []string{"Zebra"}},
+		{"blog/test.html", []string{"Integration Post"}, nil},
+		{"tags/integration.html", []string{"<a href=../blog/test.html>"}, nil},
+		{"notes.html", []string{"<h1>Field Notes</h1>", "<a href=notes/aardvark/index.html>Aardvark</a>"}, []string{"Zebra"}},
+		{"notes/2.html", []string{"<a href=../notes/zebra/index.html>Zebra</a>"}, nil},
+		{"notes/zebra/index.html", []string{"Zebra"}, nil},
+		{"rss.xml", []string{"https://example.com/blog/test.html"}, []string{"notes/"}},
+		{"search-index.json", []string{`"url":"notes/zebra/index.html","collection":"notes"`}, nil},
+		{"api/manifest.json", []string{`"collection":"blog"`}, []string{"notes/"}},
+		{"sitemap.xml", []string{"https://example.com/notes.html", "https://example.com/notes/zebra/index.html"}, nil},
+	}
+	for _, c := range checks {
+		data, err := fs.ReadFile(site, c.file)
+		if err != nil {
+			t.Errorf("Expected %s in the build: %v", c.file, err)
+			continue
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Expected %s to contain %q, got %s", c.file, want, data)
+			}
+		}
+		for _, notWant := range c.notWant {
+			if strings.Contains(string(data), notWant) {
+				t.Errorf("Expected %s not to contain %q, got %s", c.file, notWant, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// defaultCompressMinSize skips files too small for compression to pay off.
+const defaultCompressMinSize = 1024
+
+// defaultCompressExtensions lists the text formats worth precompressing.
+var defaultCompressExtensions = []string{".html", ".css", ".js", ".json", ".svg", ".xml", ".txt"}
+
+// precompressedEncodings maps content codings to the sibling file extension, in the
+// order servers should prefer them.
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// CompressStats summarizes a precompression run.
+type CompressStats struct {
+	Files    int
+	Original int64
+	Gzip     int64
+	Brotli   int64
+}
+
+// CompressFiles writes .gz and .br siblings next to every compressible file in distDir
+// that is at least cfg.MinSize bytes. A variant is only kept when it is smaller than
+// the original.
+func CompressFiles(out Output, distDir string, cfg CompressConfig) (*CompressStats, error) {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = defaultCompressMinSize
+	}
+	if cfg.GzipLevel == 0 {
+		cfg.GzipLevel = gzip.BestCompression
+	}
+	if cfg.BrotliLevel == 0 {
+		cfg.BrotliLevel = brotli.BestCompression
+	}
+	extensions := cfg.Extensions
+	if len(extensions) == 0 {
+		extensions = defaultCompressExtensions
+	}
+	formats := make(map[string]bool)
+	for _, format := range cfg.Formats {
+		formats[format] = true
+	}
+	if len(formats) == 0 {
+		formats = map[string]bool{"gzip": true, "br": true}
+	}
+	compressible := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		compressible[strings.ToLower(ext)] = true
+	}
+
+	var paths []string
+	err := out.Walk(distDir, func(p string) error {
+		if compressible[strings.ToLower(filepath.Ext(p))] {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to precompress outputs: %w", err)
+	}
+
+	// Brotli at high levels is CPU bound, so files are compressed on every core.
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		stats    = &CompressStats{}
+		jobs     = make(chan string)
+	)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				fileStats, err := compressFile(out, p, cfg, formats)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				stats.add(fileStats)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range paths {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to precompress outputs: %w", firstErr)
+	}
+	return stats, nil
+}
+
+func (s *CompressStats) add(o CompressStats) {
+	s.Files += o.Files
+	s.Original += o.Original
+	s.Gzip += o.Gzip
+	s.Brotli += o.Brotli
+}
+
+// compressFile writes the enabled variants of the file at p when it is large enough.
+func compressFile(out Output, p string, cfg CompressConfig, formats map[string]bool) (CompressStats, error) {
+	var stats CompressStats
+	data, err := out.ReadFile(p)
+	if err != nil {
+		return stats, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if len(data) < cfg.MinSize {
+		return stats, nil
+	}
+
+	stats.Files = 1
+	stats.Original = int64(len(data))
+	if formats["gzip"] {
+		n, err := writeCompressed(out, p+".gz", data, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, cfg.GzipLevel)
+		})
+		if err != nil {
+			return stats, err
+		}
+		stats.Gzip = n
+	}
+	if formats["br"] {
+		n, err := writeCompressed(out, p+".br", data, func(w io.Writer) (io.WriteCloser, error) {
+			return brotli.NewWriterLevel(w, cfg.BrotliLevel), nil
+		})
+		if err != nil {
+			return stats, err
+		}
+		stats.Brotli = n
+	}
+	return stats, nil
+}
+
+// WriteReport prints the total size of the precompressed variants.
+func (s *CompressStats) WriteReport(w io.Writer) {
+	if s.Files == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Precompression: %d files, %d bytes", s.Files, s.Original)
+	if s.Gzip > 0 {
+		fmt.Fprintf(w, ", gzip %d bytes", s.Gzip)
+	}
+	if s.Brotli > 0 {
+		fmt.Fprintf(w, ", brotli %d bytes", s.Brotli)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeCompressed encodes data with the writer returned by newWriter and saves it to
+// path in out, returning the size written. Variants that do not shrink the file are skipped
+// and reported at the original size.
+func writeCompressed(out Output, path string, data []byte, newWriter func(io.Writer) (io.WriteCloser, error)) (int64, error) {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create encoder for %s: %w", path, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return 0, fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if buf.Len() >= len(data) {
+		return int64(len(data)), nil
+	}
+	if err := out.WriteFile(path, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return int64(buf.Len()), nil
+}
+
+// encodingQualities parses an Accept-Encoding header value into the q-value of each
+// content coding it lists, lowercased. Codings without a q parameter get 1.
+func encodingQualities(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+	return qualities
+}
+
+// encodingQuality returns the q-value qualities gives coding, falling back to the
+// "*" wildcard. Codings the header does not mention are not acceptable.
+func encodingQuality(qualities map[string]float64, coding string) float64 {
+	if q, ok := qualities[coding]; ok {
+		return q
+	}
+	return qualities["*"]
+}
+
+// PrecompressedVariant returns the precompressed sibling of name in fsys that best
+// matches the Accept-Encoding header along with its content coding: the one with the
+// highest q-value, preferring brotli on a tie. It returns an empty encoding when the
+// client accepts none of the variants in fsys.
+func PrecompressedVariant(fsys fs.FS, name, acceptEncoding string) (string, string) {
+	qualities := encodingQualities(acceptEncoding)
+	var bestVariant, bestEncoding string
+	bestQ := 0.0
+	for _, enc := range precompressedEncodings {
+		q := encodingQuality(qualities, enc.encoding)
+		if q <= bestQ {
+			continue
+		}
+		variant := name + enc.extension
+		if info, err := fs.Stat(fsys, variant); err == nil && !info.IsDir() {
+			bestVariant, bestEncoding, bestQ = variant, enc.encoding, q
+		}
+	}
+	return bestVariant, bestEncoding
+}
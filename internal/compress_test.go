@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressFiles(t *testing.T) {
+	distDir := t.TempDir()
+	large := strings.Repeat("<p>compressible text</p>", 200)
+	files := map[string]string{
+		"index.html": large,
+		"small.css":  "body{}",
+		"image.png":  large,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(distDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := CompressFiles(DiskOutput{}, distDir, CompressConfig{MinSize: 100})
+	if err != nil {
+		t.Fatalf("CompressFiles() error = %v", err)
+	}
+	if stats.Files != 1 {
+		t.Errorf("Expected 1 compressed file, got %d", stats.Files)
+	}
+
+	gz, err := os.Open(filepath.Join(distDir, "index.html.gz"))
+	if err != nil {
+		t.Fatalf("Missing gzip variant: %v", err)
+	}
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != large {
+		t.Error("Gzip variant does not round-trip")
+	}
+
+	br, err := os.ReadFile(filepath.Join(distDir, "index.html.br"))
+	if err != nil {
+		t.Fatalf("Missing brotli variant: %v", err)
+	}
+	if got, _ := io.ReadAll(brotli.NewReader(bytes.NewReader(br))); string(got) != large {
+		t.Error("Brotli variant does not round-trip")
+	}
+
+	for _, name := range []string{"small.css.gz", "image.png.gz", "image.png.br"} {
+		if _, err := os.Stat(filepath.Join(distDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Did not expect %s", name)
+		}
+	}
+
+	t.Run("Gzip Only", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "a.js"), []byte(large), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CompressFiles(DiskOutput{}, dir, CompressConfig{Formats: []string{"gzip"}, MinSize: 100}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "a.js.br")); !os.IsNotExist(err) {
+			t.Error("Brotli variant must not be written when disabled")
+		}
+	})
+}
+
+func TestPrecompressedVariant(t *testing.T) {
+	dir := t.TempDir()
+	fsys := os.DirFS(dir)
+	for _, name := range []string{"styles.css", "styles.css.gz", "styles.css.br"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate, br", "br"},
+		{"gzip", "gzip"},
+		{"br;q=0, gzip;q=0.8", "gzip"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"gzip;q=0.5, br;q=0.5", "br"},
+		{"BR", "br"},
+		{"*", "br"},
+		{"br;q=0, *", "gzip"},
+		{"gzip, *;q=0", "gzip"},
+		{"*;q=0", ""},
+		{"br;level=1;q=0", ""},
+		{"identity", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		variant, encoding := PrecompressedVariant(fsys, "styles.css", tt.accept)
+		if encoding != tt.want {
+			t.Errorf("PrecompressedVariant(%q) encoding = %q, want %q", tt.accept, encoding, tt.want)
+		}
+		if encoding != "" && !strings.HasPrefix(variant, "styles.css.") {
+			t.Errorf("Unexpected variant path %q", variant)
+		}
+	}
+
+	if _, encoding := PrecompressedVariant(fsys, "missing.css", "br, gzip"); encoding != "" {
+		t.Error("Expected no variant for a file without siblings")
+	}
+}
+
+func TestRunPipelineWithoutCompression(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	config, err := os.ReadFile(filepath.Join(configDir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compress := "compress:\n  enabled: true\n  formats: [gzip]\n  minSize: 1\n  extensions: [.html]\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), append(config, compress...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	index := `{{ define "content" }}` + strings.Repeat("<p>compressible text</p>", 200) + `{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "index.html"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, skip := range []bool{false, true} {
+		out := NewMemoryOutput()
+		opts := []PipelineOption{WithOutput(out)}
+		if skip {
+			opts = append(opts, WithoutCompression())
+		}
+		if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, opts...); err != nil {
+			t.Fatalf("RunPipeline failed: %v", err)
+		}
+		_, err := out.ReadFile(filepath.Join(distDir, "index.html.gz"))
+		if compressed := err == nil; compressed == skip {
+			t.Errorf("WithoutCompression = %v: index.html.gz written = %v", skip, compressed)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -16,6 +18,11 @@ import (
 
 // LoadConfig reads and parses all YAML files under configDir, populating a SiteConfig.
 func LoadConfig(configDir string) (*SiteConfig, error) {
+	return LoadConfigFS(os.DirFS(configDir))
+}
+
+// LoadConfigFS reads and parses the YAML config files at the root of fsys.
+func LoadConfigFS(fsys fs.FS) (*SiteConfig, error) {
 	var config SiteConfig
 
 	files := []string{
@@ -24,8 +31,7 @@ func LoadConfig(configDir string) (*SiteConfig, error) {
 	}
 
 	for _, file := range files {
-		path := filepath.Join(configDir, file)
-		data, err := os.ReadFile(path)
+		data, err := fs.ReadFile(fsys, file)
 		if err != nil {
 			return nil, err
 		}
@@ -35,11 +41,83 @@ func LoadConfig(configDir string) (*SiteConfig, error) {
 		}
 	}
 
+	// Build hooks name paths of the site's own checkout, so they live in an optional
+	// file the default theme does not ship.
+	data, err := fs.ReadFile(fsys, "hooks.yaml")
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := yaml.Load(data, &config); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.validateCollections(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
-// ParsePost reads a Markdown file, decodes its frontmatter, and parses Markdown to HTML.
-func ParsePost(path string) (*Post, error) {
+// ParseOption customizes how ParsePost converts Markdown.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	images     *ImageProcessor
+	history    map[string][]Revision
+	pathPrefix *string
+}
+
+// WithImageProcessor resizes local images referenced by posts through p.
+func WithImageProcessor(p *ImageProcessor) ParseOption {
+	return func(c *parseConfig) { c.images = p }
+}
+
+// WithHistory attaches git revisions to posts, keyed by source file name as
+// returned by LoadPostHistory.
+func WithHistory(history map[string][]Revision) ParseOption {
+	return func(c *parseConfig) { c.history = history }
+}
+
+// WithPathPrefix prefixes the URLs of images rendered into posts with prefix, the
+// relative path from a post back to the site root. It defaults to "../".
+func WithPathPrefix(prefix string) ParseOption {
+	return func(c *parseConfig) { c.pathPrefix = &prefix }
+}
+
+// newMarkdown builds the Goldmark converter shared by all content. baseDir resolves
+// relative image paths, and rendered image URLs are prefixed with pathPrefix.
+func newMarkdown(cfg parseConfig, baseDir, pathPrefix string) goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			highlighting.NewHighlighting(
+				highlighting.WithStyle("monokai"),
+			),
+			&imageExtension{renderer: &imageRenderer{
+				images:     cfg.images,
+				baseDir:    baseDir,
+				pathPrefix: pathPrefix,
+			}},
+		),
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+		),
+	)
+}
+
+// readPostSource splits a Markdown file into its decoded frontmatter and body. The
+// body is nil when the file has no frontmatter block.
+func readPostSource(path string) (Frontmatter, []byte, error) {
+	var fm Frontmatter
+	body, err := readMarkdownSource(path, &fm)
+	return fm, body, err
+}
+
+// readMarkdownSource decodes the frontmatter block of a Markdown file into fm and
+// returns the body that follows it, or nil when the file has no frontmatter.
+func readMarkdownSource(path string, fm interface{}) ([]byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -50,25 +128,32 @@ func ParsePost(path string) (*Post, error) {
 		return nil, nil
 	}
 
-	var fm Frontmatter
-	if err := yaml.Load([]byte(parts[1]), &fm); err != nil {
+	if err := yaml.Load([]byte(parts[1]), fm); err != nil {
+		return nil, err
+	}
+	return []byte(parts[2]), nil
+}
+
+// ParsePost reads a Markdown file, decodes its frontmatter, and parses Markdown to HTML.
+func ParsePost(path string, opts ...ParseOption) (*Post, error) {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	fm, body, err := readPostSource(path)
+	if err != nil || body == nil {
 		return nil, err
 	}
 
 	var buf bytes.Buffer
-	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("monokai"),
-			),
-		),
-		goldmark.WithRendererOptions(
-			html.WithUnsafe(),
-		),
-	)
+	prefix := "../"
+	if cfg.pathPrefix != nil {
+		prefix = *cfg.pathPrefix
+	}
+	md := newMarkdown(cfg, filepath.Dir(path), prefix)
 
-	if err := md.Convert([]byte(parts[2]), &buf); err != nil {
+	if err := md.Convert(body, &buf); err != nil {
 		return nil, err
 	}
 
@@ -76,15 +161,28 @@ func ParsePost(path string) (*Post, error) {
 
 	slug := strings.TrimSuffix(filepath.Base(path), ".md")
 
-	return &Post{
+	post := &Post{
 		Frontmatter: fm,
 		Slug:        slug,
 		Content:     content,
-	}, nil
+		WordCount:   len(strings.Fields(string(body))),
+		Revisions:   cfg.history[filepath.Base(path)],
+	}
+	// The oldest revision added the file, which says nothing about later edits, and a
+	// shallow clone shows every file as added by its first commit. Only a post with
+	// more than one revision counts as modified by git.
+	switch {
+	case !fm.Updated.IsZero():
+		post.LastModified = fm.Updated
+	case len(post.Revisions) > 1:
+		post.LastModified = post.Revisions[0].Date
+	}
+
+	return post, nil
 }
 
 // GetPosts scans contentDir for markdown files, parsing and sorting them descending by date.
-func GetPosts(contentDir string) ([]Post, error) {
+func GetPosts(contentDir string, opts ...ParseOption) ([]Post, error) {
 	var posts []Post
 
 	files, err := os.ReadDir(contentDir)
@@ -94,7 +192,7 @@ func GetPosts(contentDir string) ([]Post, error) {
 
 	for _, file := range files {
 		if !file.IsDir() && strings.HasSuffix(file.Name(), ".md") {
-			post, err := ParsePost(filepath.Join(contentDir, file.Name()))
+			post, err := ParsePost(filepath.Join(contentDir, file.Name()), opts...)
 			if err != nil {
 				return nil, err
 			}
@@ -104,8 +202,13 @@ func GetPosts(contentDir string) ([]Post, error) {
 		}
 	}
 
+	// Newest first; posts published at the same time fall back to slug order so
+	// repeated builds list them identically.
 	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].Date.After(posts[j].Date)
+		if !posts[i].Date.Equal(posts[j].Date) {
+			return posts[i].Date.After(posts[j].Date)
+		}
+		return posts[i].Slug < posts[j].Slug
 	})
 
 	return posts, nil
@@ -113,12 +216,23 @@ func GetPosts(contentDir string) ([]Post, error) {
 
 // ProcessPosts groups loaded blog posts by year and tags, and computes related post links.
 func ProcessPosts(posts []Post) *ContentData {
+	return processCollection(DefaultCollection(), posts)
+}
+
+// processCollection sets the collection and path of each entry of c, then groups them
+// by year and tags and computes related links, keeping the given order.
+func processCollection(c CollectionConfig, posts []Post) *ContentData {
+	for i := range posts {
+		posts[i].Collection = c.Name
+		posts[i].Path = c.EntryPath(posts[i].Slug)
+	}
 	data := &ContentData{
 		Posts:       posts,
 		PostsByTag:  make(map[string][]Post),
 		PostsByYear: make(map[int][]Post),
 		TagCounts:   make(map[string]int),
 	}
+	data.Collections = []Collection{{CollectionConfig: c, Content: data}}
 
 	tagMap := make(map[string]bool)
 	var archiveYears []int
@@ -182,6 +296,7 @@ func ProcessPosts(posts []Post) *ContentData {
 			data.Posts[i].RelatedPosts = append(data.Posts[i].RelatedPosts, RelatedPost{
 				Title: data.Posts[s.idx].Title,
 				Slug:  data.Posts[s.idx].Slug,
+				Path:  data.Posts[s.idx].Path,
 			})
 		}
 	}
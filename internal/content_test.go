@@ -53,6 +53,19 @@ projects:
 			},
 			wantErr: false,
 		},
+		{
+			name: "Hooks File",
+			files: map[string]string{
+				"config.yaml":   `landing: {title: "My Site"}`,
+				"projects.yaml": `projects: []`,
+				"hooks.yaml":    "hooks:\n  preBuild:\n    - name: css\n      command: tailwindcss\n",
+			},
+			validate: func(t *testing.T, cfg *SiteConfig) {
+				if len(cfg.Hooks.PreBuild) != 1 || cfg.Hooks.PreBuild[0].Command != "tailwindcss" {
+					t.Errorf("Expected the pre-build hook from hooks.yaml, got %+v", cfg.Hooks.PreBuild)
+				}
+			},
+		},
 		{
 			name: "Missing File",
 			files: map[string]string{
@@ -316,6 +329,26 @@ Content`,
 			},
 			wantErr: false,
 		},
+		{
+			name: "Equal Dates Sorted By Slug",
+			files: map[string]string{
+				"beta.md":  "---\ntitle: \"Beta\"\ndate: 2023-01-01T00:00:00Z\n---\nContent",
+				"alpha.md": "---\ntitle: \"Alpha\"\ndate: 2023-01-01T00:00:00Z\n---\nContent",
+				"gamma.md": "---\ntitle: \"Gamma\"\ndate: 2023-01-01T00:00:00Z\n---\nContent",
+				"newer.md": "---\ntitle: \"Newer\"\ndate: 2023-02-01T00:00:00Z\n---\nContent",
+			},
+			validate: func(t *testing.T, posts []Post) {
+				var got []string
+				for _, p := range posts {
+					got = append(got, p.Slug)
+				}
+				want := "newer,alpha,beta,gamma"
+				if strings.Join(got, ",") != want {
+					t.Errorf("Expected order %s, got %v", want, got)
+				}
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,615 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/html"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 2
+
+// maxLCSCells bounds the line diff's dynamic programming table; larger changes are
+// reported as a full replacement of the differing region.
+const maxLCSCells = 4_000_000
+
+// FileDiff describes how a single file changed between two builds.
+type FileDiff struct {
+	Path  string
+	Kind  string
+	Lines []string
+}
+
+// TreeDiff is the result of comparing two build output directories.
+type TreeDiff struct {
+	Added    []string
+	Removed  []string
+	Modified []FileDiff
+}
+
+// Empty reports whether the two trees were identical.
+func (d *TreeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// assetNames maps fingerprinted asset names back to their stable names. It is built
+// from the asset manifests of the builds being compared, so only real asset hashes
+// are stripped and other hex runs like commit IDs still show up as changes. A nil
+// assetNames leaves everything untouched.
+type assetNames struct {
+	paths    map[string]string
+	replacer *strings.Replacer
+}
+
+// loadAssetNames reads the default asset manifest of each build in fsyss. Builds
+// without one, such as those that do not fingerprint, contribute nothing.
+func loadAssetNames(fsyss ...fs.FS) (*assetNames, error) {
+	names := &assetNames{paths: make(map[string]string)}
+	var pairs []string
+	for _, fsys := range fsyss {
+		manifest, err := LoadAssetManifest(fsys, defaultAssetManifest)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for rel, hashed := range manifest.Assets {
+			names.paths[hashed] = rel
+			// Pages may link an asset relative to their own directory, so
+			// references are matched by base name.
+			pairs = append(pairs, path.Base(hashed), path.Base(rel))
+		}
+	}
+	names.replacer = strings.NewReplacer(pairs...)
+	return names, nil
+}
+
+// path returns the stable name of the output at p.
+func (a *assetNames) path(p string) string {
+	if a == nil {
+		return p
+	}
+	if rel, ok := a.paths[p]; ok {
+		return rel
+	}
+	return p
+}
+
+// text strips asset hashes from the references in a document.
+func (a *assetNames) text(s string) string {
+	if a == nil {
+		return s
+	}
+	return a.replacer.Replace(s)
+}
+
+// listOutputs maps the stable path of every file in fsys to its actual path,
+// skipping precompressed variants.
+func listOutputs(fsys fs.FS, names *assetNames) (map[string]string, error) {
+	files := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := path.Ext(p); ext == ".gz" || ext == ".br" {
+			return nil
+		}
+		files[names.path(p)] = p
+		return nil
+	})
+	return files, err
+}
+
+// DiffTrees compares two build output directories. HTML pages are compared as
+// normalized text, JSON documents semantically, and everything else byte for byte.
+func DiffTrees(oldDir, newDir string) (*TreeDiff, error) {
+	names, err := loadAssetNames(os.DirFS(oldDir), os.DirFS(newDir))
+	if err != nil {
+		return nil, err
+	}
+	oldFiles, err := listOutputs(os.DirFS(oldDir), names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", oldDir, err)
+	}
+	newFiles, err := listOutputs(os.DirFS(newDir), names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", newDir, err)
+	}
+
+	result := &TreeDiff{}
+	for key, rel := range newFiles {
+		if _, ok := oldFiles[key]; !ok {
+			result.Added = append(result.Added, rel)
+		}
+	}
+	for key, rel := range oldFiles {
+		newRel, ok := newFiles[key]
+		if !ok {
+			result.Removed = append(result.Removed, rel)
+			continue
+		}
+		oldData, err := os.ReadFile(filepath.Join(oldDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		newData, err := os.ReadFile(filepath.Join(newDir, filepath.FromSlash(newRel)))
+		if err != nil {
+			return nil, err
+		}
+		if fd, changed := diffFile(key, oldData, newData, names); changed {
+			result.Modified = append(result.Modified, fd)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Slice(result.Modified, func(i, j int) bool { return result.Modified[i].Path < result.Modified[j].Path })
+	return result, nil
+}
+
+// OutputDigest identifies the content of a build output independently of asset
+// hashes, so a build can be compared with one whose files are gone.
+type OutputDigest struct {
+	Path string `json:"path"`
+	Sum  string `json:"sum"`
+}
+
+// DigestOutputs hashes every output in fsys, keyed by its unfingerprinted path and
+// skipping precompressed variants. Hashes are stripped using the build's own asset
+// manifest.
+func DigestOutputs(fsys fs.FS) (map[string]OutputDigest, error) {
+	names, err := loadAssetNames(fsys)
+	if err != nil {
+		return nil, err
+	}
+	files, err := listOutputs(fsys, names)
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]OutputDigest, len(files))
+	for key, rel := range files {
+		data, err := fs.ReadFile(fsys, rel)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(names.text(string(data))))
+		digests[key] = OutputDigest{Path: rel, Sum: hex.EncodeToString(sum[:])}
+	}
+	return digests, nil
+}
+
+// ChangedDigests lists the outputs of newDigests that are missing from oldDigests
+// or whose content differs, mapping each unfingerprinted path to its actual path.
+// Asset hashes are ignored, so restyling does not flag every page that links the
+// stylesheet.
+func ChangedDigests(oldDigests, newDigests map[string]OutputDigest) map[string]string {
+	changed := make(map[string]string)
+	for key, d := range newDigests {
+		if old, ok := oldDigests[key]; !ok || old.Sum != d.Sum {
+			changed[key] = d.Path
+		}
+	}
+	return changed
+}
+
+// diffFile compares two versions of the file at rel, returning false when they are
+// equivalent once normalized and stripped of the asset hashes known to names.
+func diffFile(rel string, oldData, newData []byte, names *assetNames) (FileDiff, bool) {
+	if bytes.Equal(oldData, newData) {
+		return FileDiff{}, false
+	}
+	fd := FileDiff{Path: rel}
+
+	switch strings.ToLower(path.Ext(rel)) {
+	case ".html", ".htm":
+		fd.Kind = "html"
+		fd.Lines = DiffLines(normalizeHTML(oldData, names), normalizeHTML(newData, names))
+	case ".json":
+		var oldValue, newValue interface{}
+		if json.Unmarshal([]byte(names.text(string(oldData))), &oldValue) == nil &&
+			json.Unmarshal([]byte(names.text(string(newData))), &newValue) == nil {
+			fd.Kind = "json"
+			fd.Lines = DiffJSON(oldValue, newValue)
+			break
+		}
+		fd.Kind = "text"
+		fd.Lines = DiffLines(splitLines(oldData), splitLines(newData))
+	case ".css", ".js", ".svg", ".xml", ".txt":
+		fd.Kind = "text"
+		fd.Lines = DiffLines(splitLines([]byte(names.text(string(oldData)))), splitLines([]byte(names.text(string(newData)))))
+	default:
+		fd.Kind = "binary"
+		fd.Lines = []string{fmt.Sprintf("binary file changed (%d -> %d bytes)", len(oldData), len(newData))}
+	}
+	if fd.Kind != "binary" && len(fd.Lines) == 0 {
+		return FileDiff{}, false
+	}
+	return fd, true
+}
+
+func splitLines(data []byte) []string {
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+// normalizeHTML reflows a minified page into one tag or text run per line with
+// collapsed whitespace and asset hashes removed, so diffs follow content rather than
+// formatting.
+func normalizeHTML(data []byte, names *assetNames) []string {
+	var lines []string
+	var tag strings.Builder
+	emit := func(s string) {
+		if s = strings.Join(strings.Fields(s), " "); s != "" {
+			lines = append(lines, names.text(s))
+		}
+	}
+
+	l := html.NewLexer(parse.NewInputBytes(data))
+	for {
+		tt, raw := l.Next()
+		switch tt {
+		case html.ErrorToken:
+			if l.Err() != io.EOF {
+				emit(string(raw))
+			}
+			return lines
+		case html.StartTagToken:
+			tag.Reset()
+			tag.WriteString("<" + string(l.Text()))
+		case html.AttributeToken:
+			tag.WriteString(" " + string(l.AttrKey()))
+			if val := l.AttrVal(); len(val) > 0 {
+				tag.WriteString("=" + strings.Trim(string(val), `"'`))
+			}
+		case html.StartTagCloseToken, html.StartTagVoidToken:
+			tag.WriteString(">")
+			emit(tag.String())
+		case html.EndTagToken:
+			emit("</" + string(l.Text()) + ">")
+		case html.CommentToken:
+		default:
+			emit(string(raw))
+		}
+	}
+}
+
+type lineOp struct {
+	kind byte
+	text string
+}
+
+// DiffLines returns a unified-style diff of two line slices with hunk headers and a
+// few lines of context, or nil when they are equal.
+func DiffLines(a, b []string) []string {
+	ops := lineOps(a, b)
+
+	var out []string
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run-end > 2*diffContext || run == len(ops) {
+				break
+			}
+			end = run
+		}
+		stop := end + diffContext
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		out = append(out, "@@")
+		for _, op := range ops[start:stop] {
+			out = append(out, string(op.kind)+op.text)
+		}
+		i = stop
+	}
+	return out
+}
+
+// lineOps aligns a and b, trimming their common prefix and suffix before running a
+// longest-common-subsequence match on the remainder.
+func lineOps(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []lineOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	ops = append(ops, lcsOps(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, lineOp{' ', line})
+	}
+	return ops
+}
+
+func lcsOps(a, b []string) []lineOp {
+	var ops []lineOp
+	if len(a)*len(b) > maxLCSCells {
+		for _, line := range a {
+			ops = append(ops, lineOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, lineOp{'+', line})
+		}
+		return ops
+	}
+
+	// table[i][j] holds the LCS length of a[i:] and b[j:].
+	table := make([][]int32, len(a)+1)
+	for i := range table {
+		table[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{' ', a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			ops = append(ops, lineOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, lineOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, lineOp{'+', b[j]})
+	}
+	return ops
+}
+
+// jsonKeyFields are the fields used to match array elements between two documents,
+// so inserting a post reports one addition instead of shifting every later index.
+var jsonKeyFields = []string{"slug", "id", "url", "name", "title"}
+
+// DiffJSON reports the semantic differences between two decoded JSON documents as
+// lines prefixed with "+" (added), "-" (removed) or "~" (changed) and a JSON path.
+func DiffJSON(oldValue, newValue interface{}) []string {
+	var out []string
+	diffJSONValue("$", oldValue, newValue, &out)
+	return out
+}
+
+func diffJSONValue(p string, oldValue, newValue interface{}, out *[]string) {
+	switch o := oldValue.(type) {
+	case map[string]interface{}:
+		n, ok := newValue.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			child := p + "." + k
+			switch {
+			case !inOld:
+				*out = append(*out, "+ "+child+": "+jsonString(nv))
+			case !inNew:
+				*out = append(*out, "- "+child+": "+jsonString(ov))
+			default:
+				diffJSONValue(child, ov, nv, out)
+			}
+		}
+		return
+	case []interface{}:
+		n, ok := newValue.([]interface{})
+		if !ok {
+			break
+		}
+		diffJSONArray(p, o, n, out)
+		return
+	}
+
+	if jsonString(oldValue) != jsonString(newValue) {
+		*out = append(*out, "~ "+p+": "+jsonString(oldValue)+" -> "+jsonString(newValue))
+	}
+}
+
+// diffJSONArray matches elements by a shared key field when every element has one,
+// and by index otherwise.
+func diffJSONArray(p string, o, n []interface{}, out *[]string) {
+	field := arrayKeyField(o, n)
+	if field == "" {
+		for i := 0; i < len(o) || i < len(n); i++ {
+			child := fmt.Sprintf("%s[%d]", p, i)
+			switch {
+			case i >= len(n):
+				*out = append(*out, "- "+child+": "+jsonString(o[i]))
+			case i >= len(o):
+				*out = append(*out, "+ "+child+": "+jsonString(n[i]))
+			default:
+				diffJSONValue(child, o[i], n[i], out)
+			}
+		}
+		return
+	}
+
+	key := func(v interface{}) string { return jsonString(v.(map[string]interface{})[field]) }
+	oldByKey := make(map[string]interface{}, len(o))
+	for _, v := range o {
+		oldByKey[key(v)] = v
+	}
+	newByKey := make(map[string]bool, len(n))
+	for _, v := range n {
+		k := key(v)
+		newByKey[k] = true
+		child := fmt.Sprintf("%s[%s=%s]", p, field, k)
+		if ov, ok := oldByKey[k]; ok {
+			diffJSONValue(child, ov, v, out)
+		} else {
+			*out = append(*out, "+ "+child)
+		}
+	}
+	for _, v := range o {
+		if k := key(v); !newByKey[k] {
+			*out = append(*out, fmt.Sprintf("- %s[%s=%s]", p, field, k))
+		}
+	}
+}
+
+func arrayKeyField(arrays ...[]interface{}) string {
+	for _, field := range jsonKeyFields {
+		ok := true
+		seen := 0
+		for _, arr := range arrays {
+			for _, v := range arr {
+				m, isMap := v.(map[string]interface{})
+				if !isMap {
+					return ""
+				}
+				if _, has := m[field]; !has {
+					ok = false
+				}
+				seen++
+			}
+		}
+		if ok && seen > 0 {
+			return field
+		}
+	}
+	return ""
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// WriteDiffMarkdown renders d as a Markdown report suitable for a pull request
+// comment. Each file's diff is collapsed and truncated to maxLines lines.
+func WriteDiffMarkdown(w io.Writer, d *TreeDiff, title string, maxLines int) {
+	fmt.Fprintf(w, "## %s\n\n", title)
+	if d.Empty() {
+		fmt.Fprintln(w, "No changes to the generated site.")
+		return
+	}
+
+	fmt.Fprintf(w, "| Added | Removed | Modified |\n| ---: | ---: | ---: |\n| %d | %d | %d |\n\n", len(d.Added), len(d.Removed), len(d.Modified))
+
+	writeList := func(heading string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "### %s\n\n", heading)
+		for _, p := range paths {
+			fmt.Fprintf(w, "- `%s`\n", p)
+		}
+		fmt.Fprintln(w)
+	}
+	writeList("Added", d.Added)
+	writeList("Removed", d.Removed)
+
+	if len(d.Modified) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "### Modified\n\n")
+	for _, fd := range d.Modified {
+		lines := fd.Lines
+		truncated := 0
+		if maxLines > 0 && len(lines) > maxLines {
+			truncated = len(lines) - maxLines
+			lines = lines[:maxLines]
+		}
+		fmt.Fprintf(w, "<details>\n<summary><code>%s</code> (%s)</summary>\n\n```diff\n", fd.Path, fd.Kind)
+		for _, line := range lines {
+			fmt.Fprintln(w, strings.ReplaceAll(line, "```", "` ` `"))
+		}
+		if truncated > 0 {
+			fmt.Fprintf(w, "… %d more lines\n", truncated)
+		}
+		fmt.Fprint(w, "```\n\n</details>\n\n")
+	}
+}
+
+// BuildRevision checks out rev of the repository at repoDir into a temporary git
+// worktree and builds it with that revision's own generator. It returns the built
+// dist directory and a cleanup func that removes the worktree.
+func BuildRevision(repoDir, rev string) (string, func(), error) {
+	worktree, err := os.MkdirTemp("", "mehub-diff-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create worktree dir: %w", err)
+	}
+	cleanup := func() {
+		exec.Command("git", "-C", repoDir, "worktree", "remove", "--force", worktree).Run()
+		os.RemoveAll(worktree)
+	}
+
+	if out, err := exec.Command("git", "-C", repoDir, "worktree", "add", "--detach", worktree, rev).CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to check out %s: %w\n%s", rev, err, out)
+	}
+
+	cmd := exec.Command("go", "run", "./cmd/ssg")
+	cmd.Dir = worktree
+	if out, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to build %s: %w\n%s", rev, err, out)
+	}
+	return filepath.Join(worktree, "dist"), cleanup, nil
+}
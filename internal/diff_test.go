@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{
+			name: "Equal",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: nil,
+		},
+		{
+			name: "Changed Line With Context",
+			a:    []string{"1", "2", "3", "4", "5", "6", "7"},
+			b:    []string{"1", "2", "3", "x", "5", "6", "7"},
+			want: []string{"@@", " 2", " 3", "-4", "+x", " 5", " 6"},
+		},
+		{
+			name: "Separate Hunks",
+			a:    []string{"a", "1", "2", "3", "4", "5", "6", "b"},
+			b:    []string{"A", "1", "2", "3", "4", "5", "6", "B"},
+			want: []string{"@@", "-a", "+A", " 1", " 2", "@@", " 5", " 6", "-b", "+B"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	decode := func(s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	oldDoc := decode(`{"name":"site","posts":[{"slug":"a","title":"A"},{"slug":"b","title":"B"}],"skills":["go"]}`)
+	newDoc := decode(`{"name":"site","posts":[{"slug":"new","title":"N"},{"slug":"a","title":"A2"}],"skills":["go","k8s"],"extra":1}`)
+
+	got := DiffJSON(oldDoc, newDoc)
+	want := []string{
+		`+ $.extra: 1`,
+		`+ $.posts[slug="new"]`,
+		`~ $.posts[slug="a"].title: "A" -> "A2"`,
+		`- $.posts[slug="b"]`,
+		`+ $.skills[1]: "k8s"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffJSON() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	oldDir := writeTree(t, map[string]string{
+		"index.html":            `<html><head><link href=styles.11111111.css></head><body><h1>Hello</h1><p>Same</p></body></html>`,
+		"styles.11111111.css":   "body{}",
+		"removed.html":          "<p>gone</p>",
+		"search-index.json":     `[{"slug":"a","title":"A"}]`,
+		"logo.png":              "png1",
+		"index.html.gz":         "ignored",
+		"unchanged.txt":         "same",
+		"api/manifest.json":     `{"b":1,"a":2}`,
+		"blog/reformatted.html": "<p>Text</p>",
+		"asset-manifest.json":   `{"styles.css":"styles.11111111.css"}`,
+		"build.txt":             "build v1.0123abcd.txt",
+	})
+	newDir := writeTree(t, map[string]string{
+		"index.html":            `<html><head><link href=styles.22222222.css></head><body><h1>Hello, world</h1><p>Same</p></body></html>`,
+		"styles.22222222.css":   "body{color:red}",
+		"added.html":            "<p>new</p>",
+		"search-index.json":     `[{"slug":"a","title":"A!"}]`,
+		"logo.png":              "png2",
+		"unchanged.txt":         "same",
+		"api/manifest.json":     `{"a":2,"b":1}`,
+		"blog/reformatted.html": "<p>\n  Text\n</p>",
+		"asset-manifest.json":   `{"styles.css":"styles.22222222.css"}`,
+		"build.txt":             "build v1.4567cdef.txt",
+	})
+
+	d, err := DiffTrees(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffTrees() error = %v", err)
+	}
+	if !reflect.DeepEqual(d.Added, []string{"added.html"}) {
+		t.Errorf("Added = %v", d.Added)
+	}
+	if !reflect.DeepEqual(d.Removed, []string{"removed.html"}) {
+		t.Errorf("Removed = %v", d.Removed)
+	}
+
+	kinds := make(map[string]string)
+	for _, fd := range d.Modified {
+		kinds[fd.Path] = fd.Kind
+	}
+	wantKinds := map[string]string{
+		"index.html":        "html",
+		"styles.css":        "text",
+		"search-index.json": "json",
+		"logo.png":          "binary",
+		"build.txt":         "text",
+	}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("Modified kinds = %v, want %v", kinds, wantKinds)
+	}
+
+	for _, fd := range d.Modified {
+		if fd.Path != "index.html" {
+			continue
+		}
+		joined := strings.Join(fd.Lines, "\n")
+		if !strings.Contains(joined, "-Hello\n+Hello, world") {
+			t.Errorf("Expected text change, got:\n%s", joined)
+		}
+		if strings.Contains(joined, "styles.") && strings.Contains(joined, "-<link") {
+			t.Errorf("Fingerprint changes must not show up in page diffs, got:\n%s", joined)
+		}
+	}
+
+	var report bytes.Buffer
+	WriteDiffMarkdown(&report, d, "Site diff", 1)
+	for _, want := range []string{"## Site diff", "| 1 | 1 | 5 |", "- `added.html`", "<summary><code>index.html</code> (html)</summary>", "more lines"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report.String())
+		}
+	}
+
+	var empty bytes.Buffer
+	WriteDiffMarkdown(&empty, &TreeDiff{}, "Site diff", 0)
+	if !strings.Contains(empty.String(), "No changes") {
+		t.Errorf("Expected empty report, got %q", empty.String())
+	}
+}
+
+func TestChangedDigests(t *testing.T) {
+	digest := func(files map[string]string) map[string]OutputDigest {
+		t.Helper()
+		out := NewMemoryOutput()
+		for rel, content := range files {
+			if err := out.WriteFile("dist/"+rel, []byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		digests, err := DigestOutputs(out.FS("dist"))
+		if err != nil {
+			t.Fatalf("DigestOutputs() error = %v", err)
+		}
+		return digests
+	}
+
+	oldDigests := digest(map[string]string{
+		"index.html":          `<link href=styles.11111111.css><h1>Home</h1>`,
+		"styles.11111111.css": "body{}",
+		"asset-manifest.json": `{"styles.css":"styles.11111111.css"}`,
+	})
+	newDigests := digest(map[string]string{
+		"index.html":             `<link href=styles.22222222.css><h1>Home</h1>`,
+		"styles.22222222.css":    "body{color:red}",
+		"styles.22222222.css.gz": "compressed",
+		"blog/new.html":          "<p>new</p>",
+		"asset-manifest.json":    `{"styles.css":"styles.22222222.css"}`,
+	})
+
+	if _, ok := newDigests["styles.css.gz"]; ok {
+		t.Error("Expected precompressed variants to be skipped")
+	}
+	want := map[string]string{
+		"styles.css":    "styles.22222222.css",
+		"blog/new.html": "blog/new.html",
+	}
+	if got := ChangedDigests(oldDigests, newDigests); !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedDigests() = %v, want %v", got, want)
+	}
+}
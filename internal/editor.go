@@ -0,0 +1,356 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed editor.html
+var editorPage []byte
+
+var (
+	editorSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	editorTagPattern  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// PostEditor serves a browser editor for the Markdown posts in BlogDir, the source
+// directory of the collection it edits. It only answers requests from the local
+// machine.
+type PostEditor struct {
+	BlogDir string
+}
+
+// EditorPost is a post as exchanged with the editor page. Revision is the content
+// hash of the file when it was loaded, used to refuse saves over newer edits.
+type EditorPost struct {
+	Slug        string      `json:"slug"`
+	Frontmatter Frontmatter `json:"frontmatter"`
+	Body        string      `json:"body"`
+	Revision    string      `json:"revision"`
+}
+
+// editorListItem summarizes one post for the editor's file list.
+type editorListItem struct {
+	Slug  string    `json:"slug"`
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+	Draft bool      `json:"draft"`
+}
+
+// Handler serves the editor under prefix:
+//
+//	prefix          the editor page
+//	prefix+posts    the posts in BlogDir and the TagCounts of published posts
+//	prefix+post     one post's frontmatter and body (GET ?slug=) or a save (POST)
+//	prefix+preview  the HTML ParsePost would render for a Markdown body (POST)
+func (e *PostEditor) Handler(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(editorPage)
+	})
+	mux.HandleFunc(prefix+"posts", e.handleList)
+	mux.HandleFunc(prefix+"post", e.handlePost)
+	mux.HandleFunc(prefix+"preview", e.handlePreview)
+	return localOnly(mux)
+}
+
+// localOnly rejects requests from other machines, requests addressed to any host
+// name but the loopback ones, which a DNS-rebinding page would send, and cross-site
+// requests that a page elsewhere could make through the user's browser.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			http.Error(w, "only local connections are accepted", http.StatusForbidden)
+			return
+		}
+		if !isLoopbackHost(r.Host) {
+			http.Error(w, "only requests for localhost are accepted", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether a Host header names localhost, 127.0.0.1 or [::1],
+// with or without a port.
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	switch strings.Trim(host, "[]") {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func (e *PostEditor) handleList(w http.ResponseWriter, r *http.Request) {
+	files, err := filepath.Glob(filepath.Join(e.BlogDir, "*.md"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := []editorListItem{}
+	var published []Post
+	for _, file := range files {
+		fm, body, err := readPostSource(file)
+		if err != nil || body == nil {
+			continue
+		}
+		slug := strings.TrimSuffix(filepath.Base(file), ".md")
+		items = append(items, editorListItem{Slug: slug, Title: fm.Title, Date: fm.Date, Draft: fm.Draft})
+		if !fm.Draft {
+			published = append(published, Post{Frontmatter: fm, Slug: slug})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Date.Equal(items[j].Date) {
+			return items[i].Date.After(items[j].Date)
+		}
+		return items[i].Slug < items[j].Slug
+	})
+
+	writeInspectJSON(w, map[string]interface{}{
+		"posts":     items,
+		"tagCounts": ProcessPosts(published).TagCounts,
+	})
+}
+
+func (e *PostEditor) handlePost(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		e.load(w, r.URL.Query().Get("slug"))
+	case http.MethodPost:
+		var post EditorPost
+		if !decodeEditorJSON(w, r, &post) {
+			return
+		}
+		e.save(w, post)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (e *PostEditor) load(w http.ResponseWriter, slug string) {
+	if !editorSlugPattern.MatchString(slug) {
+		http.Error(w, "invalid slug", http.StatusBadRequest)
+		return
+	}
+	path := filepath.Join(e.BlogDir, slug+".md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fm, body, err := readPostSource(path)
+	if err != nil || body == nil {
+		http.Error(w, fmt.Sprintf("failed to parse frontmatter of %s: %v", slug, err), http.StatusUnprocessableEntity)
+		return
+	}
+	writeInspectJSON(w, EditorPost{
+		Slug:        slug,
+		Frontmatter: fm,
+		Body:        strings.TrimLeft(string(body), "\n"),
+		Revision:    contentRevision(data),
+	})
+}
+
+func (e *PostEditor) save(w http.ResponseWriter, post EditorPost) {
+	if problems := ValidateEditorPost(post); len(problems) > 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": problems})
+		return
+	}
+
+	path := filepath.Join(e.BlogDir, post.Slug+".md")
+	current, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		current = nil
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if current != nil && post.Revision == "" {
+		http.Error(w, post.Slug+".md already exists; pick another slug or load it first", http.StatusConflict)
+		return
+	}
+	if current != nil && contentRevision(current) != post.Revision {
+		http.Error(w, post.Slug+".md changed on disk since it was loaded; reload it before saving", http.StatusConflict)
+		return
+	}
+
+	data := FormatPostSource(post.Frontmatter, post.Body)
+	if err := writeFileAtomic(path, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeInspectJSON(w, map[string]string{"slug": post.Slug, "revision": contentRevision(data)})
+}
+
+func (e *PostEditor) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Body string `json:"body"`
+	}
+	if !decodeEditorJSON(w, r, &req) {
+		return
+	}
+
+	// Image URLs are prefixed for the site root, since the preview is not served
+	// from blog/ like the rendered post.
+	var buf bytes.Buffer
+	if err := newMarkdown(parseConfig{}, e.BlogDir, "/").Convert([]byte(req.Body), &buf); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeInspectJSON(w, map[string]string{"html": buf.String()})
+}
+
+// decodeEditorJSON reads a JSON request body into v, answering with an error and
+// returning false when the request is not JSON.
+func decodeEditorJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "expected application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(v); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// ValidateEditorPost returns a message for every field of post that would not
+// build: the slug and tags must be lowercase and hyphenated, a title, date and
+// body are required, and an updated date cannot precede the publish date.
+func ValidateEditorPost(post EditorPost) []string {
+	var problems []string
+	if !editorSlugPattern.MatchString(post.Slug) {
+		problems = append(problems, "slug must be lowercase letters, digits and single hyphens")
+	}
+	fm := post.Frontmatter
+	if strings.TrimSpace(fm.Title) == "" {
+		problems = append(problems, "title is required")
+	}
+	if fm.Date.IsZero() {
+		problems = append(problems, "date is required")
+	}
+	if !fm.Updated.IsZero() && fm.Updated.Before(fm.Date) {
+		problems = append(problems, "updated must not be before date")
+	}
+	seen := make(map[string]bool)
+	for _, tag := range fm.Tags {
+		if !editorTagPattern.MatchString(tag) {
+			problems = append(problems, fmt.Sprintf("tag %q must be lowercase letters, digits and single hyphens", tag))
+		}
+		if seen[tag] {
+			problems = append(problems, fmt.Sprintf("tag %q is listed twice", tag))
+		}
+		seen[tag] = true
+	}
+	if strings.TrimSpace(post.Body) == "" {
+		problems = append(problems, "body is required")
+	}
+	return problems
+}
+
+// FormatPostSource writes frontmatter and body in the layout the posts in blog/
+// use: quoted strings, date-only dates and an inline tag list.
+func FormatPostSource(fm Frontmatter, body string) []byte {
+	quote := func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+	date := func(t time.Time) string {
+		if t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)) {
+			return t.Format("2006-01-02")
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", quote(fm.Title))
+	fmt.Fprintf(&b, "description: %s\n", quote(fm.Description))
+	fmt.Fprintf(&b, "date: %s\n", date(fm.Date))
+	if !fm.Updated.IsZero() {
+		fmt.Fprintf(&b, "updated: %s\n", date(fm.Updated))
+	}
+	tags := make([]string, len(fm.Tags))
+	for i, tag := range fm.Tags {
+		tags[i] = quote(tag)
+	}
+	fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(tags, ", "))
+	if fm.Draft {
+		b.WriteString("draft: true\n")
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(strings.TrimRight(strings.TrimLeft(body, "\n"), " \t\n"))
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+// contentRevision identifies a version of a file by its content hash.
+func contentRevision(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// writeFileAtomic replaces path with data through a temporary sibling, so the
+// dev server never rebuilds from a half-written post.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
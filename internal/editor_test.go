@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEditor(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	blogDir := writeTree(t, map[string]string{
+		"hello.md": "---\ntitle: \"Hello\"\ndescription: \"Greeting\"\ndate: 2024-01-06\ntags: [\"go\", \"growth\"]\n---\n\n## Hi\n\nBody text.\n",
+		"other.md": "---\ntitle: Other\ndate: 2024-02-01\ntags: [\"go\"]\n---\n\nMore.\n",
+		"draft.md": "---\ntitle: Draft\ndate: 2024-03-01\ntags: [\"secret\"]\ndraft: true\n---\n\nWIP\n",
+	})
+	return (&PostEditor{BlogDir: blogDir}).Handler("/editor/"), blogDir
+}
+
+func editorRequest(t *testing.T, h http.Handler, method, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = httptest.NewRequest(method, target, strings.NewReader(string(data)))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, target, nil)
+	}
+	req.RemoteAddr = "127.0.0.1:50000"
+	req.Host = "localhost:8080"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPostEditorAccess(t *testing.T) {
+	h, _ := newTestEditor(t)
+
+	remote := httptest.NewRequest(http.MethodGet, "/editor/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, remote)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected remote clients to be rejected, got %d", rec.Code)
+	}
+
+	crossSite := httptest.NewRequest(http.MethodPost, "/editor/preview", strings.NewReader(`{"body":"x"}`))
+	crossSite.RemoteAddr = "127.0.0.1:50000"
+	crossSite.Host = "localhost:8080"
+	crossSite.Header.Set("Origin", "https://evil.example")
+	crossSite.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, crossSite)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected cross-origin requests to be rejected, got %d", rec.Code)
+	}
+
+	for _, host := range []string{"evil.example:8080", "evil.example", "127.0.0.1.evil.example:8080", "10.0.0.5:8080"} {
+		rebound := httptest.NewRequest(http.MethodPost, "/editor/post", strings.NewReader(`{}`))
+		rebound.RemoteAddr = "127.0.0.1:50000"
+		rebound.Host = host
+		rebound.Header.Set("Origin", "http://"+host)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, rebound)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected requests for host %s to be rejected, got %d", host, rec.Code)
+		}
+	}
+	for _, host := range []string{"localhost", "127.0.0.1:8080", "[::1]:8080"} {
+		req := httptest.NewRequest(http.MethodGet, "/editor/", nil)
+		req.RemoteAddr = "127.0.0.1:50000"
+		req.Host = host
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected requests for host %s to be accepted, got %d", host, rec.Code)
+		}
+	}
+
+	page := editorRequest(t, h, http.MethodGet, "/editor/", nil)
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), "<title>Post editor</title>") {
+		t.Errorf("Expected editor page, got %d", page.Code)
+	}
+}
+
+func TestPostEditorListAndLoad(t *testing.T) {
+	h, _ := newTestEditor(t)
+
+	var list struct {
+		Posts []struct {
+			Slug  string `json:"slug"`
+			Draft bool   `json:"draft"`
+		} `json:"posts"`
+		TagCounts map[string]int `json:"tagCounts"`
+	}
+	rec := editorRequest(t, h, http.MethodGet, "/editor/posts", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Invalid list response %s: %v", rec.Body.String(), err)
+	}
+	var slugs []string
+	for _, p := range list.Posts {
+		slugs = append(slugs, p.Slug)
+	}
+	if !reflect.DeepEqual(slugs, []string{"draft", "other", "hello"}) {
+		t.Errorf("Expected drafts listed newest first, got %v", slugs)
+	}
+	if !reflect.DeepEqual(list.TagCounts, map[string]int{"go": 2, "growth": 1}) {
+		t.Errorf("Expected TagCounts of published posts, got %v", list.TagCounts)
+	}
+
+	var post EditorPost
+	rec = editorRequest(t, h, http.MethodGet, "/editor/post?slug=hello", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatalf("Invalid post response %s: %v", rec.Body.String(), err)
+	}
+	if post.Frontmatter.Title != "Hello" || post.Body != "## Hi\n\nBody text.\n" || post.Revision == "" {
+		t.Errorf("Unexpected post %+v", post)
+	}
+
+	for _, target := range []string{"/editor/post?slug=../secret", "/editor/post?slug=missing"} {
+		if rec := editorRequest(t, h, http.MethodGet, target, nil); rec.Code == http.StatusOK {
+			t.Errorf("Expected %s to fail", target)
+		}
+	}
+}
+
+func TestPostEditorSave(t *testing.T) {
+	h, blogDir := newTestEditor(t)
+
+	var post EditorPost
+	json.Unmarshal(editorRequest(t, h, http.MethodGet, "/editor/post?slug=hello", nil).Body.Bytes(), &post)
+	staleRevision := post.Revision
+
+	post.Frontmatter.Title = `Hello "World"`
+	post.Frontmatter.Updated = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	post.Body = "## Hi\n\nEdited.\n"
+	if rec := editorRequest(t, h, http.MethodPost, "/editor/post", post); rec.Code != http.StatusOK {
+		t.Fatalf("Save failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	saved, err := ParsePost(filepath.Join(blogDir, "hello.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Title != `Hello "World"` || !saved.Updated.Equal(post.Frontmatter.Updated) || !strings.Contains(saved.Content, "Edited.") {
+		t.Errorf("Unexpected saved post %+v", saved)
+	}
+
+	post.Revision = staleRevision
+	if rec := editorRequest(t, h, http.MethodPost, "/editor/post", post); rec.Code != http.StatusConflict {
+		t.Errorf("Expected conflict for a stale revision, got %d", rec.Code)
+	}
+
+	post.Slug, post.Revision = "other", ""
+	if rec := editorRequest(t, h, http.MethodPost, "/editor/post", post); rec.Code != http.StatusConflict {
+		t.Errorf("Expected conflict when a new post reuses a slug, got %d", rec.Code)
+	}
+
+	post.Slug = "brand-new"
+	if rec := editorRequest(t, h, http.MethodPost, "/editor/post", post); rec.Code != http.StatusOK {
+		t.Errorf("Expected new post to be created, got %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(blogDir, "brand-new.md")); err != nil {
+		t.Error(err)
+	}
+
+	invalid := EditorPost{Slug: "Bad Slug", Frontmatter: Frontmatter{Tags: []string{"Go"}}}
+	rec := editorRequest(t, h, http.MethodPost, "/editor/post", invalid)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "title is required") {
+		t.Errorf("Expected validation errors, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPostEditorPreview(t *testing.T) {
+	h, _ := newTestEditor(t)
+
+	rec := editorRequest(t, h, http.MethodPost, "/editor/preview", map[string]string{"body": "## Title\n\n```go\nfunc main() {}\n```\n\n| a |\n|---|\n| b |\n"})
+	var res struct {
+		HTML string `json:"html"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Invalid preview response %s: %v", rec.Body.String(), err)
+	}
+	for _, want := range []string{"<h2>Title</h2>", "<pre", "<table>"} {
+		if !strings.Contains(res.HTML, want) {
+			t.Errorf("Expected preview to contain %q, got %s", want, res.HTML)
+		}
+	}
+
+	plain := httptest.NewRequest(http.MethodPost, "/editor/preview", strings.NewReader("body"))
+	plain.RemoteAddr = "127.0.0.1:50000"
+	plain.Host = "localhost:8080"
+	plainRec := httptest.NewRecorder()
+	h.ServeHTTP(plainRec, plain)
+	if plainRec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected non-JSON requests to be rejected, got %d", plainRec.Code)
+	}
+}
+
+func TestValidateEditorPost(t *testing.T) {
+	date := time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)
+	valid := EditorPost{Slug: "hello-world", Body: "Text", Frontmatter: Frontmatter{Title: "Hello", Date: date, Tags: []string{"go", "data-structure"}}}
+
+	tests := []struct {
+		name   string
+		modify func(*EditorPost)
+		want   []string
+	}{
+		{"Valid", func(p *EditorPost) {}, nil},
+		{"Bad Slug", func(p *EditorPost) { p.Slug = "Hello_World" }, []string{"slug must be lowercase letters, digits and single hyphens"}},
+		{"Missing Fields", func(p *EditorPost) { p.Frontmatter.Title = " "; p.Frontmatter.Date = time.Time{}; p.Body = "\n" }, []string{"title is required", "date is required", "body is required"}},
+		{"Updated Before Date", func(p *EditorPost) { p.Frontmatter.Updated = date.AddDate(0, 0, -1) }, []string{"updated must not be before date"}},
+		{"Bad And Duplicate Tags", func(p *EditorPost) { p.Frontmatter.Tags = []string{"Go", "go", "go"} }, []string{`tag "Go" must be lowercase letters, digits and single hyphens`, `tag "go" is listed twice`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			p.Frontmatter.Tags = append([]string(nil), valid.Frontmatter.Tags...)
+			tt.modify(&p)
+			if got := ValidateEditorPost(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateEditorPost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPostSource(t *testing.T) {
+	fm := Frontmatter{
+		Title:       `Say "hi"`,
+		Description: "Short",
+		Date:        time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC),
+		Tags:        []string{"go", "growth"},
+	}
+	want := "---\ntitle: \"Say \\\"hi\\\"\"\ndescription: \"Short\"\ndate: 2024-01-06\ntags: [\"go\", \"growth\"]\n---\n\n## Body\n"
+	if got := string(FormatPostSource(fm, "\n\n## Body\n\n\n")); got != want {
+		t.Errorf("FormatPostSource() =\n%s\nwant\n%s", got, want)
+	}
+
+	fm.Draft = true
+	fm.Updated = time.Date(2024, 2, 1, 15, 30, 0, 0, time.UTC)
+	got := string(FormatPostSource(fm, "x"))
+	for _, line := range []string{"updated: 2024-02-01T15:30:00Z\n", "draft: true\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("Expected %q in\n%s", line, got)
+		}
+	}
+}
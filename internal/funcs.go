@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordsPerMinute is the reading speed readingTime assumes.
+const wordsPerMinute = 200
+
+// dateLayouts names the date formats templates use most, so they need not spell out
+// Go reference layouts. Any other layout passed to date is used as is.
+var dateLayouts = map[string]string{
+	"iso":     "2006-01-02",
+	"short":   "Jan 2, 2006",
+	"long":    "January 02, 2006",
+	"month":   "January 2006",
+	"rfc3339": time.RFC3339,
+	"rfc1123": time.RFC1123Z,
+}
+
+// templateFuncs returns the template function library New registers next to the
+// string and math helpers:
+//
+//	date "long" .Date          formats a time with a named or Go reference layout
+//	truncate 140 .Description  shortens text to a number of characters at a word break
+//	markdownify .Config.X      renders inline Markdown from config strings to HTML
+//	absURL "rss.xml"           resolves a site path against Landing.URL
+//	relURL "blog.html"         resolves a site path against the page's PathPrefix
+//	slugify "Go & Web"         turns text into a URL-safe slug: go-web
+//	jsonify .Tags              encodes a value as JSON for use in scripts
+//	dict "Post" . "N" 1        builds a map, mostly to pass several values to a partial
+//	slice "a" "b"              builds a list
+//	pluralize 3 "post"         picks the singular or plural of a word for a count
+//	readingTime .Post          estimates minutes to read a post or HTML string
+func (g *SiteGenerator) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"date":        formatDate,
+		"truncate":    truncate,
+		"markdownify": markdownify,
+		"absURL":      g.absURL,
+		"relURL":      relURL(""),
+		"slugify":     slugify,
+		"jsonify":     jsonify,
+		"dict":        dict,
+		"slice":       func(items ...interface{}) []interface{} { return items },
+		"pluralize":   pluralize,
+		"readingTime": readingTime,
+	}
+}
+
+// pageFuncs returns the template functions whose result depends on the page being
+// rendered. RenderPage rebinds them before executing each page.
+func pageFuncs(data PageData) template.FuncMap {
+	return template.FuncMap{"relURL": relURL(data.PathPrefix)}
+}
+
+// formatDate formats t with a layout named in dateLayouts or a Go reference layout.
+// A zero time formats as an empty string.
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if named, ok := dateLayouts[layout]; ok {
+		layout = named
+	}
+	return t.Format(layout)
+}
+
+// truncate shortens s to at most n characters, cutting at the last word break and
+// appending an ellipsis. Strings that already fit are returned unchanged.
+func truncate(n int, s string) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	cut := string(runes[:n])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// markdownify renders Markdown to HTML with the same converter as posts. A single
+// paragraph is unwrapped so the result can be placed inline.
+func markdownify(s string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := newMarkdown(parseConfig{}, "", "").Convert([]byte(s), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	out := strings.TrimSpace(buf.String())
+	if inner, ok := strings.CutPrefix(out, "<p>"); ok && strings.Count(out, "<p>") == 1 {
+		out = strings.TrimSuffix(inner, "</p>")
+	}
+	return template.HTML(out), nil
+}
+
+// isAbsoluteURL reports whether link points outside the site or only within the page.
+func isAbsoluteURL(link string) bool {
+	return strings.Contains(link, "://") || strings.HasPrefix(link, "//") ||
+		strings.HasPrefix(link, "#") || strings.HasPrefix(link, "mailto:")
+}
+
+// absURL resolves a site path against Landing.URL. Absolute URLs are returned as is.
+func (g *SiteGenerator) absURL(link string) string {
+	if isAbsoluteURL(link) {
+		return link
+	}
+	return strings.TrimSuffix(g.Config.Landing.URL, "/") + "/" + strings.TrimPrefix(link, "/")
+}
+
+// rootPath returns the path of the site root on its host, such as "/" or "/docs/".
+// It is the PathPrefix of pages like 404.html that are served at any depth, so
+// their links resolve from wherever they are shown.
+func (g *SiteGenerator) rootPath() string {
+	u, err := url.Parse(g.Config.Landing.URL)
+	if err != nil {
+		return "/"
+	}
+	return strings.TrimSuffix(u.Path, "/") + "/"
+}
+
+// relURL returns a function resolving site paths against prefix, the relative path
+// from a page back to the site root. Absolute URLs are returned as is.
+func relURL(prefix string) func(string) string {
+	return func(link string) string {
+		if isAbsoluteURL(link) {
+			return link
+		}
+		if rel := prefix + strings.TrimPrefix(link, "/"); rel != "" {
+			return rel
+		}
+		return "./"
+	}
+}
+
+var slugSeparators = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// slugify lowercases s and joins its letters and digits with single hyphens.
+func slugify(s string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// jsonify encodes v as JSON that html/template places verbatim inside scripts.
+func jsonify(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return template.JS(data), nil
+}
+
+// dict builds a map from alternating keys and values so a partial can be given more
+// than one value: {{ template "link" dict "Href" .Path "Text" .Title }}.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects key and value pairs, got %d arguments", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// pluralize returns singular when count is one and the plural otherwise, which is
+// singular with an "s" unless given explicitly.
+func pluralize(count int, singular string, plural ...string) string {
+	if count == 1 {
+		return singular
+	}
+	if len(plural) > 0 {
+		return plural[0]
+	}
+	return singular + "s"
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// readingTime estimates the whole minutes needed to read v, at least one. v is a
+// Post, a word count, or text that may contain HTML.
+func readingTime(v interface{}) (int, error) {
+	var words int
+	switch v := v.(type) {
+	case Post:
+		words = v.WordCount
+	case *Post:
+		words = v.WordCount
+	case int:
+		words = v
+	case string:
+		words = len(strings.Fields(htmlTags.ReplaceAllString(v, " ")))
+	case template.HTML:
+		words = len(strings.Fields(htmlTags.ReplaceAllString(string(v), " ")))
+	default:
+		return 0, fmt.Errorf("readingTime cannot count the words of %T", v)
+	}
+	return max(1, (words+wordsPerMinute-1)/wordsPerMinute), nil
+}
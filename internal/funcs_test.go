@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	gen := New(createConfig(), "")
+	date := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		data    interface{}
+		want    string
+		wantErr bool
+	}{
+		{"date - Named Layout", `{{ date "long" . }}`, date, "March 05, 2024", false},
+		{"date - ISO", `{{ date "iso" . }}`, date, "2024-03-05", false},
+		{"date - Go Layout", `{{ date "02/01 15:04" . }}`, date, "05/03 14:30", false},
+		{"date - Zero", `{{ date "long" . }}`, time.Time{}, "", false},
+		{"truncate - Fits", `{{ truncate 20 . }}`, "Short text", "Short text", false},
+		{"truncate - Word Break", `{{ . | truncate 18 }}`, "Building a static site, in Go", "Building a static…", false},
+		{"truncate - Runes", `{{ truncate 3 . }}`, "héllo", "hél…", false},
+		{"markdownify - Inline", `{{ markdownify . }}`, "Hello **world**", "Hello <strong>world</strong>", false},
+		{"markdownify - Paragraphs", `{{ markdownify . }}`, "One\n\nTwo", "<p>One</p>\n<p>Two</p>", false},
+		{"absURL - Path", `{{ absURL "blog/post.html" }}`, nil, "http://example.com/blog/post.html", false},
+		{"absURL - Rooted", `{{ absURL "/rss.xml" }}`, nil, "http://example.com/rss.xml", false},
+		{"absURL - External", `{{ absURL "https://go.dev/" }}`, nil, "https://go.dev/", false},
+		{"relURL - Root Page", `{{ relURL "/blog.html" }}`, nil, "blog.html", false},
+		{"slugify", `{{ slugify . }}`, "Go & Web: Part 2!", "go-web-part-2", false},
+		{"slugify - Unicode", `{{ slugify . }}`, "Café Notes", "café-notes", false},
+		{"jsonify - Script", `<script>const tags = {{ jsonify . }};</script>`, []string{"go", "web"}, `<script>const tags = ["go","web"];</script>`, false},
+		{"dict", `{{ with dict "A" 1 "B" "two" }}{{ .A }}-{{ .B }}{{ end }}`, nil, "1-two", false},
+		{"dict - Odd Arguments", `{{ dict "A" }}`, nil, "", true},
+		{"dict - Non-String Key", `{{ dict 1 "A" }}`, nil, "", true},
+		{"slice", `{{ range slice "a" "b" }}{{ . }};{{ end }}`, nil, "a;b;", false},
+		{"pluralize - One", `1 {{ pluralize 1 "post" }}`, nil, "1 post", false},
+		{"pluralize - Many", `3 {{ pluralize 3 "post" }}`, nil, "3 posts", false},
+		{"pluralize - Irregular", `{{ pluralize 0 "entry" "entries" }}`, nil, "entries", false},
+		{"readingTime - Post", `{{ readingTime . }}`, Post{WordCount: 401}, "3", false},
+		{"readingTime - Short HTML", `{{ readingTime . }}`, template.HTML("<p>a few <em>words</em></p>"), "1", false},
+		{"readingTime - Unsupported", `{{ readingTime . }}`, 1.5, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(gen.FuncMap).Parse(tt.tmpl)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var sb strings.Builder
+			err = tmpl.Execute(&sb, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && sb.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", sb.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRelURLFollowsPage(t *testing.T) {
+	gen, out := newTemplateSetGenerator(t, map[string]string{
+		"base.html":  `{{ template "content" . }}`,
+		"index.html": `{{ define "content" }}<a href="{{ relURL "/blog.html" }}">blog</a><a href="{{ relURL "https://go.dev/" }}">go</a>{{ end }}`,
+	})
+
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"", `<a href=blog.html>blog</a><a href=https://go.dev/>go</a>`},
+		{"../", `<a href=../blog.html>blog</a><a href=https://go.dev/>go</a>`},
+		{"", `<a href=blog.html>blog</a><a href=https://go.dev/>go</a>`},
+	}
+	for _, tt := range tests {
+		if err := gen.RenderPage("dist", "page.html", "index.html", "", PageData{PathPrefix: tt.prefix}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := out.ReadFile("dist/page.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), tt.want) {
+			t.Errorf("PathPrefix %q: got %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestRootPath(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/", "/"},
+		{"https://example.com", "/"},
+		{"https://example.com/docs", "/docs/"},
+		{"https://example.com/docs/", "/docs/"},
+	}
+	for _, tt := range tests {
+		cfg := createConfig()
+		cfg.Landing.URL = tt.url
+		if got := New(cfg, "").rootPath(); got != tt.want {
+			t.Errorf("rootPath() for %s = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -7,32 +7,40 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/tdewolff/minify/v2"
-	"github.com/tdewolff/minify/v2/html"
 )
 
 type SiteGenerator struct {
-	Config            *SiteConfig
-	FuncMap           template.FuncMap
-	TemplatesDir      string
-	minifier          *minify.M
-	totalOriginalSize int64
-	totalMinifiedSize int64
+	Config      *SiteConfig
+	FuncMap     template.FuncMap
+	Templates   fs.FS
+	Images      *ImageProcessor
+	Assets      *AssetManifest
+	BuildTime   time.Time
+	Inspector   *BuildInspector
+	Output      Output
+	minifier    *minify.M
+	minifyStats map[string]*MinifyStats
+	minified    map[string]bool
+	pages       map[string]SitemapEntry
+	templates   *templateSet
 }
 
 func New(cfg *SiteConfig, templatesDir string) *SiteGenerator {
-	m := minify.New()
-	m.AddFunc("text/html", html.Minify)
-
-	return &SiteGenerator{
-		Config:       cfg,
-		TemplatesDir: templatesDir,
-		minifier:     m,
+	g := &SiteGenerator{
+		Config:      cfg,
+		Templates:   os.DirFS(templatesDir),
+		BuildTime:   time.Now(),
+		Output:      DiskOutput{},
+		minifier:    newMinifier(cfg.Minify),
+		minifyStats: make(map[string]*MinifyStats),
+		minified:    make(map[string]bool),
 		FuncMap: template.FuncMap{
 			"split":             strings.Split,
 			"replace":           strings.ReplaceAll,
@@ -67,25 +75,54 @@ func New(cfg *SiteConfig, templatesDir string) *SiteGenerator {
 			},
 		},
 	}
+	g.FuncMap["srcset"] = g.srcset
+	g.FuncMap["asset"] = g.asset
+	for name, fn := range g.templateFuncs() {
+		g.FuncMap[name] = fn
+	}
+	return g
 }
 
-func (g *SiteGenerator) RenderPage(dir, filename, tmplPath string, titlePrefix string, data PageData) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create dir %s: %w", dir, err)
+// srcset returns the responsive srcset for a processed static image, or an empty
+// string when image processing is disabled or the image has no smaller variants.
+func (g *SiteGenerator) srcset(prefix, name string) string {
+	if g.Images == nil {
+		return ""
 	}
+	img, ok := g.Images.Static(name)
+	if !ok || len(img.Variants) == 0 {
+		return ""
+	}
+	return img.Srcset(prefix)
+}
 
-	basePath := filepath.Join(g.TemplatesDir, "base.html")
-	fullTmplPath := filepath.Join(g.TemplatesDir, tmplPath)
-	tmpl, err := template.New("base.html").Funcs(g.FuncMap).ParseFiles(basePath, fullTmplPath)
-	if err != nil {
-		return fmt.Errorf("failed to parse templates for %s: %w", fullTmplPath, err)
+// GenerateImages processes static images and copies every processed image into distDir.
+func (g *SiteGenerator) GenerateImages(distDir string) error {
+	if g.Images == nil {
+		return nil
+	}
+	if err := g.Images.ProcessStatic(); err != nil {
+		return err
 	}
+	return g.Images.Emit(g.Output, distDir)
+}
 
-	outputFile, err := os.Create(filepath.Join(dir, filename))
+func (g *SiteGenerator) RenderPage(dir, filename, tmplPath string, titlePrefix string, data PageData) error {
+	page, err := g.pageTemplate(tmplPath)
+	if err != nil {
+		return err
+	}
+	// The cached page is never executed itself: each render binds relURL to its own
+	// prefix on a clone, so renders neither depend on their order nor race.
+	tmpl, err := page.Clone()
 	if err != nil {
-		return fmt.Errorf("failed to create output file %s: %w", filename, err)
+		return fmt.Errorf("failed to clone template %s: %w", tmplPath, err)
+	}
+	tmpl.Funcs(pageFuncs(data))
+	layout := layoutTemplate(data.Layout)
+	if tmpl.Lookup(layout) == nil {
+		return fmt.Errorf("unknown layout %q for %s", data.Layout, tmplPath)
 	}
-	defer outputFile.Close()
 
 	title := g.Config.Landing.Title
 	if titlePrefix != "" {
@@ -93,27 +130,30 @@ func (g *SiteGenerator) RenderPage(dir, filename, tmplPath string, titlePrefix s
 	}
 
 	data.Config = g.Config
-	data.CurrentYear = time.Now().Year()
+	data.CurrentYear = g.BuildTime.Year()
 	data.Title = title
+	if data.Path == "" {
+		data.Path = filename
+	}
+	if data.StructuredData, err = g.structuredData(data, titlePrefix); err != nil {
+		return fmt.Errorf("failed to build structured data for %s: %w", filename, err)
+	}
 
 	var buf bytes.Buffer
-	if err := tmpl.ExecuteTemplate(&buf, "base.html", data); err != nil {
-		return fmt.Errorf("failed to execute template for %s: %w", fullTmplPath, err)
+	if err := tmpl.ExecuteTemplate(&buf, layout, data); err != nil {
+		return fmt.Errorf("failed to execute template for %s: %w", tmplPath, err)
 	}
 
-	originalSize := buf.Len()
-	minifiedHTML, err := g.minifier.Bytes("text/html", buf.Bytes())
+	minifiedHTML, err := g.minifyBytes("html", buf.Bytes())
 	if err != nil {
 		return fmt.Errorf("failed to minify HTML for %s: %w", filename, err)
 	}
-	minifiedSize := len(minifiedHTML)
-
-	g.totalOriginalSize += int64(originalSize)
-	g.totalMinifiedSize += int64(minifiedSize)
 
-	if _, err := outputFile.Write(minifiedHTML); err != nil {
+	if err := g.Output.WriteFile(filepath.Join(dir, filename), minifiedHTML); err != nil {
 		return fmt.Errorf("failed to write minified HTML to %s: %w", filename, err)
 	}
+	g.recordPage(data)
+	g.Inspector.recordPage(tmplPath, data)
 	return nil
 }
 
@@ -124,11 +164,11 @@ func (g *SiteGenerator) GenerateStaticPages(distDir string, data *ContentData) e
 		titlePrefix string
 		data        PageData
 	}{
-		{"index.html", "index.html", "", PageData{}},
-		{"work.html", "work.html", "Work", PageData{}},
-		{"about.html", "about.html", "About", PageData{}},
-		{"404.html", "404.html", "404 - Not Found", PageData{}},
-		{"archive.html", "archive.html", "Archive", PageData{Archive: data.PostsByYear, ArchiveYears: data.ArchiveYears}},
+		{"index.html", "index.html", "", PageData{Path: "index.html"}},
+		{"work.html", "work.html", "Work", PageData{Path: "work.html"}},
+		{"about.html", "about.html", "About", PageData{Path: "about.html"}},
+		{"404.html", "404.html", "404 - Not Found", PageData{Path: "404.html", PathPrefix: g.rootPath()}},
+		{"archive.html", "archive.html", "Archive", PageData{Path: "archive.html", Archive: data.PostsByYear, ArchiveYears: data.ArchiveYears}},
 	}
 
 	for _, p := range pages {
@@ -139,36 +179,38 @@ func (g *SiteGenerator) GenerateStaticPages(distDir string, data *ContentData) e
 	return nil
 }
 
-func (g *SiteGenerator) GenerateBlogPagination(distDir string, data *ContentData, pageSize int) error {
-	totalPages := (len(data.Posts) + pageSize - 1) / pageSize
-	for i := 0; i < totalPages; i++ {
-		startIdx := i * pageSize
-		endIdx := startIdx + pageSize
-		if endIdx > len(data.Posts) {
-			endIdx = len(data.Posts)
+// GenerateListPages renders the listing pages of every collection with a list
+// template, PageSize entries per page.
+func (g *SiteGenerator) GenerateListPages(distDir string, data *ContentData) error {
+	for _, c := range g.collections(data) {
+		if c.ListTemplate == "" {
+			continue
 		}
-		pagePosts := data.Posts[startIdx:endIdx]
-		pageNumber := i + 1
-
-		if pageNumber == 1 {
-			if err := g.RenderPage(distDir, "blog.html", "blog.html", "Blog", PageData{
-				Posts:       pagePosts,
-				CurrentPage: pageNumber,
-				TotalPages:  totalPages,
-				Tags:        data.Tags,
-				TagCounts:   data.TagCounts,
-			}); err != nil {
-				return err
+		posts := c.Content.Posts
+		pageSize := c.PageSize
+		if pageSize == 0 {
+			pageSize = max(len(posts), 1)
+		}
+		totalPages := (len(posts) + pageSize - 1) / pageSize
+		for i := 0; i < totalPages; i++ {
+			startIdx := i * pageSize
+			endIdx := min(startIdx+pageSize, len(posts))
+			pageNumber := i + 1
+
+			title := c.Title
+			if pageNumber > 1 {
+				title = fmt.Sprintf("%s - Page %d", c.Title, pageNumber)
 			}
-		} else {
-			pageDir := filepath.Join(distDir, "blog")
-			if err := g.RenderPage(pageDir, fmt.Sprintf("%d.html", pageNumber), "blog.html", fmt.Sprintf("Blog - Page %d", pageNumber), PageData{
-				Posts:       pagePosts,
+			pagePath := c.ListPath(pageNumber)
+			if err := g.RenderPage(distDir, filepath.FromSlash(pagePath), c.ListTemplate, title, PageData{
+				Path:        pagePath,
+				Posts:       posts[startIdx:endIdx],
 				CurrentPage: pageNumber,
 				TotalPages:  totalPages,
-				PathPrefix:  "../",
-				Tags:        data.Tags,
-				TagCounts:   data.TagCounts,
+				PathPrefix:  pagePathPrefix(pagePath),
+				Tags:        c.Content.Tags,
+				TagCounts:   c.Content.TagCounts,
+				Collection:  &c.CollectionConfig,
 			}); err != nil {
 				return err
 			}
@@ -177,30 +219,49 @@ func (g *SiteGenerator) GenerateBlogPagination(distDir string, data *ContentData
 	return nil
 }
 
+// GenerateTagPages renders a listing of the entries with each tag for every collection
+// with a tag URL.
 func (g *SiteGenerator) GenerateTagPages(distDir string, data *ContentData) error {
-	tagsDistDir := filepath.Join(distDir, "tags")
-	for tag, tagPosts := range data.PostsByTag {
-		if err := g.RenderPage(tagsDistDir, tag+".html", "blog.html", "#"+tag, PageData{
-			Posts:      tagPosts,
-			PathPrefix: "../",
-			Tags:       data.Tags,
-			TagCounts:  data.TagCounts,
-		}); err != nil {
-			return err
+	for _, c := range g.collections(data) {
+		if c.TagURL == "" {
+			continue
+		}
+		for tag, tagPosts := range c.Content.PostsByTag {
+			pagePath := c.TagPath(tag)
+			if err := g.RenderPage(distDir, filepath.FromSlash(pagePath), c.ListTemplate, "#"+tag, PageData{
+				Path:       pagePath,
+				Posts:      tagPosts,
+				PathPrefix: pagePathPrefix(pagePath),
+				Tags:       c.Content.Tags,
+				TagCounts:  c.Content.TagCounts,
+				Collection: &c.CollectionConfig,
+				Tag:        tag,
+			}); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// GeneratePostPages renders every entry of every collection at its entry path.
 func (g *SiteGenerator) GeneratePostPages(distDir string, data *ContentData) error {
-	blogDistDir := filepath.Join(distDir, "blog")
-	for _, post := range data.Posts {
-		p := post
-		if err := g.RenderPage(blogDistDir, post.Slug+".html", "post.html", post.Title, PageData{
-			Post:       &p,
-			PathPrefix: "../",
-		}); err != nil {
-			return err
+	for _, c := range g.collections(data) {
+		for _, post := range c.Content.Posts {
+			p := post
+			pagePath := c.EntryPath(post.Slug)
+			pageData := PageData{
+				Path:       pagePath,
+				Post:       &p,
+				PathPrefix: pagePathPrefix(pagePath),
+				Collection: &c.CollectionConfig,
+			}
+			if g.Config.OGImage.Enabled {
+				pageData.OGImage = g.Config.Landing.URL + ogImageName(pagePath)
+			}
+			if err := g.RenderPage(distDir, filepath.FromSlash(pagePath), c.Template, post.Title, pageData); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -208,10 +269,13 @@ func (g *SiteGenerator) GeneratePostPages(distDir string, data *ContentData) err
 
 func (g *SiteGenerator) GenerateSearchIndex(distDir string, data *ContentData) error {
 	var items []SearchItem
-	for _, post := range data.Posts {
+	for _, e := range sortedEntries(g.collections(data), func(c Collection) bool { return c.Search }) {
+		post := e.Post
 		items = append(items, SearchItem{
 			Title:       post.Title,
 			Slug:        post.Slug,
+			URL:         e.Path,
+			Collection:  e.Collection,
 			Description: post.Description,
 			Date:        post.Date.Format("January 02, 2006"),
 			Tags:        post.Tags,
@@ -222,19 +286,18 @@ func (g *SiteGenerator) GenerateSearchIndex(distDir string, data *ContentData) e
 	if err != nil {
 		return fmt.Errorf("failed to marshal search index: %w", err)
 	}
-
-	if err := os.WriteFile(filepath.Join(distDir, "search-index.json"), jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write search-index.json: %w", err)
+	if err := g.validateAPIOutput("search-index", jsonData); err != nil {
+		return err
 	}
-	return nil
+
+	_, err = g.writeAsset(distDir, "search-index.json", jsonData)
+	return err
 }
 
-func (g *SiteGenerator) GenerateRSS(distDir string, posts []Post) error {
-	f, err := os.Create(filepath.Join(distDir, "rss.xml"))
-	if err != nil {
-		return fmt.Errorf("failed to create rss.xml: %w", err)
-	}
-	defer f.Close()
+// GenerateRSS writes rss.xml with the entries of every collection included in the
+// feed, newest first.
+func (g *SiteGenerator) GenerateRSS(distDir string, data *ContentData) error {
+	var f bytes.Buffer
 
 	escape := func(s string) string {
 		var b strings.Builder
@@ -244,7 +307,7 @@ func (g *SiteGenerator) GenerateRSS(distDir string, posts []Post) error {
 		return b.String()
 	}
 
-	if _, err := fmt.Fprint(f, `<?xml version="1.0" encoding="UTF-8" ?>
+	if _, err := fmt.Fprint(&f, `<?xml version="1.0" encoding="UTF-8" ?>
 <rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
 <channel>
   <title>`+escape(g.Config.Landing.Title)+`</title>
@@ -255,9 +318,10 @@ func (g *SiteGenerator) GenerateRSS(distDir string, posts []Post) error {
 		return err
 	}
 
-	for _, post := range posts {
-		link := g.Config.Landing.URL + "blog/" + post.Slug + ".html"
-		if _, err := fmt.Fprintf(f, `  <item>
+	for _, e := range sortedEntries(g.collections(data), func(c Collection) bool { return c.Feed }) {
+		post := e.Post
+		link := g.Config.Landing.URL + e.Path
+		if _, err := fmt.Fprintf(&f, `  <item>
     <title>%s</title>
     <link>%s</link>
     <description>%s</description>
@@ -269,77 +333,28 @@ func (g *SiteGenerator) GenerateRSS(distDir string, posts []Post) error {
 		}
 	}
 
-	if _, err := fmt.Fprint(f, `</channel>
+	if _, err := fmt.Fprint(&f, `</channel>
 </rss>`); err != nil {
 		return err
 	}
-	return nil
-}
-
-func (g *SiteGenerator) GenerateSitemap(distDir string, posts []Post) error {
-	f, err := os.Create(filepath.Join(distDir, "sitemap.xml"))
-	if err != nil {
-		return fmt.Errorf("failed to create sitemap.xml: %w", err)
-	}
-	defer f.Close()
-
-	if _, err := fmt.Fprint(f, `<?xml version="1.0" encoding="UTF-8"?>
-<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
-`); err != nil {
-		return err
-	}
-
-	// Static Pages
-	pages := []string{"", "work.html", "about.html", "blog.html", "archive.html"}
-	for _, page := range pages {
-		if _, err := fmt.Fprintf(f, `  <url>
-    <loc>%s%s</loc>
-    <lastmod>%s</lastmod>
-  </url>
-`, g.Config.Landing.URL, page, time.Now().Format("2006-01-02")); err != nil {
-			return err
-		}
-	}
-
-	// Blog Posts
-	for _, post := range posts {
-		if _, err := fmt.Fprintf(f, `  <url>
-    <loc>%sblog/%s.html</loc>
-    <lastmod>%s</lastmod>
-  </url>
-`, g.Config.Landing.URL, post.Slug, post.Date.Format("2006-01-02")); err != nil {
-			return err
-		}
-	}
-
-	// API Manifest
-	if _, err := fmt.Fprintf(f, `  <url>
-    <loc>%sapi/manifest.json</loc>
-    <lastmod>%s</lastmod>
-  </url>
-`, g.Config.Landing.URL, time.Now().Format("2006-01-02")); err != nil {
-		return err
-	}
-
-	if _, err := fmt.Fprint(f, `</urlset>`); err != nil {
-		return err
+	if err := g.Output.WriteFile(filepath.Join(distDir, "rss.xml"), f.Bytes()); err != nil {
+		return fmt.Errorf("failed to write rss.xml: %w", err)
 	}
 	return nil
 }
 
 func (g *SiteGenerator) GenerateRegistries(distDir string, data *ContentData) error {
 	apiDir := filepath.Join(distDir, "api")
-	if err := os.MkdirAll(apiDir, 0755); err != nil {
-		return fmt.Errorf("failed to create api dir %s: %w", apiDir, err)
-	}
 
 	// Blog Items
 	var blogItems []BlogItem
-	for _, post := range data.Posts {
+	for _, e := range sortedEntries(g.collections(data), func(c Collection) bool { return c.Manifest }) {
+		post := e.Post
 		blogItems = append(blogItems, BlogItem{
 			Title:       post.Title,
 			Description: post.Description,
-			URL:         g.Config.Landing.URL + "blog/" + post.Slug + ".html",
+			URL:         g.Config.Landing.URL + e.Path,
+			Collection:  e.Collection,
 			Date:        post.Date.Format(time.RFC3339),
 			Tags:        post.Tags,
 		})
@@ -365,10 +380,11 @@ func (g *SiteGenerator) GenerateRegistries(distDir string, data *ContentData) er
 
 	// Unified MCP Manifest
 	manifest := Manifest{
-		MCPVersion: "1.0",
-		Name:       g.Config.Landing.Title,
-		URL:        g.Config.Landing.URL,
-		UpdatedAt:  time.Now().Format(time.RFC3339),
+		MCPVersion:    "1.0",
+		SchemaVersion: APISchemaVersion,
+		Name:          g.Config.Landing.Title,
+		URL:           g.Config.Landing.URL,
+		UpdatedAt:     g.BuildTime.Format(time.RFC3339),
 		Profile: ProfileRegistry{
 			URL:        g.Config.Landing.URL,
 			Title:      g.Config.Landing.Title,
@@ -386,11 +402,11 @@ func (g *SiteGenerator) GenerateRegistries(distDir string, data *ContentData) er
 		Skills:   allSkills,
 		Projects: projectItems,
 		Blog: BlogRegistry{
-			TotalPosts: len(data.Posts),
+			TotalPosts: len(blogItems),
 			Posts:      blogItems,
 		},
 	}
-	return g.writeJSON(filepath.Join(apiDir, "manifest.json"), manifest)
+	return g.writeJSON(filepath.Join(apiDir, "manifest.json"), "manifest", manifest)
 }
 
 func (g *SiteGenerator) Build(distDir string, data *ContentData) error {
@@ -398,39 +414,45 @@ func (g *SiteGenerator) Build(distDir string, data *ContentData) error {
 		name string
 		fn   func() error
 	}{
+		{"templates", g.LoadTemplates},
+		{"images", func() error { return g.GenerateImages(distDir) }},
+		{"search index", func() error { return g.GenerateSearchIndex(distDir, data) }},
 		{"static pages", func() error { return g.GenerateStaticPages(distDir, data) }},
-		{"blog pagination", func() error { return g.GenerateBlogPagination(distDir, data, 10) }},
+		{"list pages", func() error { return g.GenerateListPages(distDir, data) }},
 		{"tag pages", func() error { return g.GenerateTagPages(distDir, data) }},
 		{"post pages", func() error { return g.GeneratePostPages(distDir, data) }},
-		{"search index", func() error { return g.GenerateSearchIndex(distDir, data) }},
+		{"pages", func() error { return g.GeneratePages(distDir, data) }},
+		{"OG images", func() error { return g.GenerateOGImages(distDir, data) }},
 		{"registries", func() error { return g.GenerateRegistries(distDir, data) }},
+		{"API schemas", func() error { return g.GenerateAPISchemas(distDir) }},
 		{"llms.txt", func() error { return g.GenerateLLMsTxt(distDir) }},
-		{"RSS", func() error { return g.GenerateRSS(distDir, data.Posts) }},
-		{"sitemap", func() error { return g.GenerateSitemap(distDir, data.Posts) }},
+		{"RSS", func() error { return g.GenerateRSS(distDir, data) }},
+		{"sitemap", func() error { return g.GenerateSitemap(distDir) }},
+		{"asset manifest", func() error { return g.GenerateAssetManifest(distDir) }},
+		{"minified outputs", func() error { return g.MinifyFiles(distDir) }},
 	}
 
+	start := time.Now()
 	for _, step := range steps {
 		if err := step.fn(); err != nil {
 			return fmt.Errorf("failed to generate %s: %w", step.name, err)
 		}
+		g.Inspector.lap("build/"+step.name, &start)
 	}
 
-	if g.totalOriginalSize > 0 {
-		beforeMB := float64(g.totalOriginalSize) / 1000000.0
-		afterMB := float64(g.totalMinifiedSize) / 1000000.0
-		savings := float64(g.totalOriginalSize-g.totalMinifiedSize) / float64(g.totalOriginalSize) * 100
-		fmt.Printf("HTML minification:\nBefore: %d bytes (%.2f MB)\nAfter: %d bytes (%.2f MB)\nSavings: %.1f%%\n", g.totalOriginalSize, beforeMB, g.totalMinifiedSize, afterMB, savings)
-	}
-
+	g.WriteMinifyReport(os.Stdout)
 	return nil
 }
 
-func (g *SiteGenerator) writeJSON(path string, data interface{}) error {
+func (g *SiteGenerator) writeJSON(path, schemaName string, data interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON for %s: %w", path, err)
 	}
-	if err := os.WriteFile(path, jsonData, 0644); err != nil {
+	if err := g.validateAPIOutput(schemaName, jsonData); err != nil {
+		return err
+	}
+	if err := g.Output.WriteFile(path, jsonData); err != nil {
 		return fmt.Errorf("failed to write JSON to %s: %w", path, err)
 	}
 	return nil
@@ -483,7 +505,7 @@ func (g *SiteGenerator) GenerateLLMsTxt(distDir string) error {
 	content := []byte(sb.String())
 
 	// Write to root for LLM discovery
-	if err := os.WriteFile(filepath.Join(distDir, "llms.txt"), content, 0644); err != nil {
+	if err := g.Output.WriteFile(filepath.Join(distDir, "llms.txt"), content); err != nil {
 		return fmt.Errorf("failed to write root llms.txt: %w", err)
 	}
 
@@ -222,7 +222,7 @@ func TestGenerators(t *testing.T) {
 		},
 		{
 			name: "RSS",
-			fn:   func() error { return gen.GenerateRSS(distDir, posts) },
+			fn:   func() error { return gen.GenerateRSS(distDir, data) },
 			check: func() error {
 				_, err := os.Stat(filepath.Join(distDir, "rss.xml"))
 				return err
@@ -230,7 +230,7 @@ func TestGenerators(t *testing.T) {
 		},
 		{
 			name: "Sitemap",
-			fn:   func() error { return gen.GenerateSitemap(distDir, posts) },
+			fn:   func() error { return gen.GenerateSitemap(distDir) },
 			check: func() error {
 				_, err := os.Stat(filepath.Join(distDir, "sitemap.xml"))
 				return err
@@ -246,7 +246,7 @@ func TestGenerators(t *testing.T) {
 		},
 		{
 			name: "Blog Pagination",
-			fn:   func() error { return gen.GenerateBlogPagination(distDir, data, 10) },
+			fn:   func() error { return gen.GenerateListPages(distDir, data) },
 			check: func() error {
 				_, err := os.Stat(filepath.Join(distDir, "blog.html"))
 				return err
@@ -297,6 +297,9 @@ func TestGenerators(t *testing.T) {
 					return err
 				}
 				defer createTemplates(t, tmpDir) // Restore for next tests
+				if err := gen.LoadTemplates(); err != nil {
+					return err
+				}
 				return gen.GenerateStaticPages(distDir, data)
 			},
 			wantErr: true,
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultHistoryRevisions caps the revisions kept per post when the config leaves
+// GitHistoryConfig.Revisions unset.
+const defaultHistoryRevisions = 5
+
+// LoadPostHistory reads the commits that touched each file directly under blogDir
+// with a single git log, newest first. The result is keyed by file name and holds at
+// most limit revisions per file. Uncommitted edits are not reflected, and a file's
+// history starts at its most recent rename. Posts only count as modified once they
+// have a revision after the one that added them, so limit should be at least two.
+func LoadPostHistory(blogDir string, limit int) (map[string][]Revision, error) {
+	if limit <= 0 {
+		limit = defaultHistoryRevisions
+	}
+
+	cmd := exec.Command("git", "-c", "core.quotepath=off", "log",
+		"--format=%x1e%h%x09%cI%x09%s", "--name-only", "--relative", "--", ".")
+	cmd.Dir = blogDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git log in %s: %w: %s", blogDir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseHistory(string(out), limit)
+}
+
+// parseHistory decodes git log output where each commit starts with a record
+// separator, followed by a tab-separated hash, date and subject line and then the
+// names of the files it touched.
+func parseHistory(out string, limit int) (map[string][]Revision, error) {
+	history := make(map[string][]Revision)
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if len(lines) == 0 || lines[0] == "" {
+			continue
+		}
+
+		fields := strings.SplitN(lines[0], "\t", 3)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("unexpected git log line %q", lines[0])
+		}
+		date, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit date %q: %w", fields[1], err)
+		}
+		rev := Revision{Hash: fields[0], Date: date, Subject: fields[2]}
+
+		for _, name := range lines[1:] {
+			name = strings.TrimSpace(name)
+			if name == "" || strings.Contains(name, "/") || len(history[name]) >= limit {
+				continue
+			}
+			history[name] = append(history[name], rev)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// commitAt commits every change in dir with the given author and committer date.
+func commitAt(t *testing.T, dir, message string, date time.Time) {
+	t.Helper()
+	stamp := date.Format(time.RFC3339)
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", message}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE="+stamp,
+			"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE="+stamp,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func newHistoryRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	blogDir := filepath.Join(repo, "blog")
+	write := func(name, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(blogDir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(blogDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("edited.md", "---\ntitle: Edited\ndate: 2024-01-01T00:00:00Z\n---\nFirst draft")
+	write("pinned.md", "---\ntitle: Pinned\ndate: 2024-01-01T00:00:00Z\nupdated: 2024-02-01T00:00:00Z\n---\nBody")
+	write("imported.md", "---\ntitle: Imported\ndate: 2023-05-01T00:00:00Z\n---\nWritten long before it was committed")
+	write("nested/ignored.md", "nested")
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte("outside blog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commitAt(t, repo, "Add posts", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	write("edited.md", "---\ntitle: Edited\ndate: 2024-01-01T00:00:00Z\n---\nSecond draft")
+	commitAt(t, repo, "Fix typo", time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC))
+
+	write("edited.md", "---\ntitle: Edited\ndate: 2024-01-01T00:00:00Z\n---\nThird draft")
+	write("pinned.md", "---\ntitle: Pinned\ndate: 2024-01-01T00:00:00Z\nupdated: 2024-02-01T00:00:00Z\n---\nBody v2")
+	commitAt(t, repo, "Expand sections", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	return blogDir
+}
+
+func TestLoadPostHistory(t *testing.T) {
+	blogDir := newHistoryRepo(t)
+
+	history, err := LoadPostHistory(blogDir, 2)
+	if err != nil {
+		t.Fatalf("LoadPostHistory() error = %v", err)
+	}
+
+	edited := history["edited.md"]
+	if len(edited) != 2 {
+		t.Fatalf("Expected 2 revisions for edited.md (limit), got %+v", edited)
+	}
+	if edited[0].Subject != "Expand sections" || edited[1].Subject != "Fix typo" {
+		t.Errorf("Expected newest revisions first, got %+v", edited)
+	}
+	if want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC); !edited[0].Date.Equal(want) {
+		t.Errorf("Expected last revision at %v, got %v", want, edited[0].Date)
+	}
+	if len(edited[0].Hash) < 7 {
+		t.Errorf("Expected abbreviated hash, got %q", edited[0].Hash)
+	}
+	if _, ok := history["nested/ignored.md"]; ok {
+		t.Error("Expected nested files to be ignored")
+	}
+	if _, ok := history["README.md"]; ok {
+		t.Error("Expected files outside the blog dir to be ignored")
+	}
+
+	if _, err := LoadPostHistory(t.TempDir(), 0); err == nil {
+		t.Error("Expected an error outside a git repository")
+	}
+}
+
+func TestGetPostsWithHistory(t *testing.T) {
+	blogDir := newHistoryRepo(t)
+	history, err := LoadPostHistory(blogDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := GetPosts(blogDir, WithHistory(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySlug := make(map[string]Post)
+	for _, p := range posts {
+		bySlug[p.Slug] = p
+	}
+
+	tests := []struct {
+		slug      string
+		modified  string
+		revisions int
+		updated   bool
+	}{
+		{slug: "edited", modified: "2024-06-01", revisions: 3, updated: true},
+		{slug: "pinned", modified: "2024-02-01", revisions: 2, updated: true},
+		{slug: "imported", modified: "2023-05-01", revisions: 1, updated: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			p := bySlug[tt.slug]
+			if got := p.Modified().Format("2006-01-02"); got != tt.modified {
+				t.Errorf("Modified() = %s, want %s", got, tt.modified)
+			}
+			if len(p.Revisions) != tt.revisions {
+				t.Errorf("Expected %d revisions, got %d", tt.revisions, len(p.Revisions))
+			}
+			if p.IsUpdated() != tt.updated {
+				t.Errorf("IsUpdated() = %v, want %v", p.IsUpdated(), tt.updated)
+			}
+		})
+	}
+}
+
+func TestPostModified(t *testing.T) {
+	date := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		lastModified time.Time
+		want         time.Time
+		updated      bool
+	}{
+		{"No History", time.Time{}, date, false},
+		{"Same Day", date.Add(3 * time.Hour), date.Add(3 * time.Hour), false},
+		{"Later Edit", date.AddDate(0, 2, 0), date.AddDate(0, 2, 0), true},
+		{"Before Publish Date", date.AddDate(0, -1, 0), date, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Post{Frontmatter: Frontmatter{Date: date}, LastModified: tt.lastModified}
+			if got := p.Modified(); !got.Equal(tt.want) {
+				t.Errorf("Modified() = %v, want %v", got, tt.want)
+			}
+			if p.IsUpdated() != tt.updated {
+				t.Errorf("IsUpdated() = %v, want %v", p.IsUpdated(), tt.updated)
+			}
+		})
+	}
+}
+
+func TestParseHistory(t *testing.T) {
+	out := "\x1eabc1234\t2024-05-01T10:00:00+02:00\tEdit: tabs\tin subject\n\na.md\nsub/b.md\n\x1edef5678\t2024-04-01T10:00:00Z\tAdd\n\na.md\n"
+	history, err := parseHistory(out, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := history["a.md"]; len(got) != 2 || got[0].Subject != "Edit: tabs\tin subject" || got[0].Date.UTC().Hour() != 8 {
+		t.Errorf("Unexpected revisions %+v", got)
+	}
+	if _, ok := history["sub/b.md"]; ok {
+		t.Error("Expected nested files to be skipped")
+	}
+	if _, err := parseHistory("\x1ebroken line\n", 5); err == nil {
+		t.Error("Expected error for malformed log line")
+	}
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultHookTimeout bounds hooks that do not configure their own timeout.
+const defaultHookTimeout = 5 * time.Minute
+
+// hookWaitDelay bounds how long a timed-out hook may keep its output open, for
+// commands like npx whose child processes outlive the killed parent.
+const hookWaitDelay = 5 * time.Second
+
+// defaultHookStateFile records the input hashes of the last successful hook runs.
+const defaultHookStateFile = ".cache/hooks.json"
+
+// HookRunner executes the configured pre- and post-build hooks, skipping guarded
+// hooks whose inputs have not changed since their last successful run.
+type HookRunner struct {
+	Config HooksConfig
+	Log    *log.Logger
+
+	state map[string]string
+}
+
+// NewHookRunner loads the hook state for cfg. Output is logged through the standard
+// logger unless Log is replaced.
+func NewHookRunner(cfg HooksConfig) (*HookRunner, error) {
+	if cfg.StateFile == "" {
+		cfg.StateFile = defaultHookStateFile
+	}
+	r := &HookRunner{
+		Config: cfg,
+		Log:    log.Default(),
+		state:  make(map[string]string),
+	}
+
+	data, err := os.ReadFile(cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook state: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.state); err != nil {
+		return nil, fmt.Errorf("failed to parse hook state %s: %w", cfg.StateFile, err)
+	}
+	return r, nil
+}
+
+// PreBuild runs every pre-build hook in order, stopping at the first failure.
+func (r *HookRunner) PreBuild() error {
+	return r.runAll("pre-build", r.Config.PreBuild)
+}
+
+// PostBuild runs every post-build hook in order, stopping at the first failure.
+func (r *HookRunner) PostBuild() error {
+	return r.runAll("post-build", r.Config.PostBuild)
+}
+
+func (r *HookRunner) runAll(stage string, hooks []HookConfig) error {
+	for _, hook := range hooks {
+		if err := r.Run(stage, hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run executes a single hook, logging its combined output line by line.
+func (r *HookRunner) Run(stage string, hook HookConfig) error {
+	name := hook.Name
+	if name == "" {
+		name = hook.Command
+	}
+	key := stage + "/" + name
+
+	var inputsHash string
+	if len(hook.Inputs) > 0 {
+		hash, err := hashHookInputs(hook)
+		if err != nil {
+			return fmt.Errorf("failed to hash inputs for hook %s: %w", name, err)
+		}
+		if r.state[key] == hash && outputsExist(hook.Outputs) {
+			r.Log.Printf("[%s] skipped: inputs unchanged", name)
+			return nil
+		}
+		inputsHash = hash
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Dir = hook.Dir
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(hook.Env))
+	for k := range hook.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, k+"="+hook.Env[k])
+	}
+
+	start := time.Now()
+	out, err := cmd.CombinedOutput()
+	r.logOutput(name, out)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook %s timed out after %v", stage, name, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook %s failed: %w", stage, name, err)
+	}
+	r.Log.Printf("[%s] finished in %v", name, time.Since(start).Round(time.Millisecond))
+
+	if inputsHash != "" {
+		r.state[key] = inputsHash
+		if err := r.saveState(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HookRunner) logOutput(name string, out []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			r.Log.Printf("[%s] %s", name, line)
+		}
+	}
+}
+
+func (r *HookRunner) saveState() error {
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hook state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Config.StateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create hook state dir: %w", err)
+	}
+	if err := os.WriteFile(r.Config.StateFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write hook state: %w", err)
+	}
+	return nil
+}
+
+// hashHookInputs hashes the hook's command line, directory and environment together
+// with the path and content of every file matched by its input globs, descending into
+// matched directories. Declared outputs are ignored so a hook writing inside one of
+// its input directories does not invalidate itself.
+func hashHookInputs(hook HookConfig) (string, error) {
+	skip := make(map[string]bool, len(hook.Outputs))
+	for _, output := range hook.Outputs {
+		skip[filepath.Clean(output)] = true
+	}
+
+	files := make(map[string]bool)
+	for _, pattern := range hook.Inputs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid input pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				if !skip[filepath.Clean(p)] {
+					files[p] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "command\x00%s\x00dir\x00%s\x00", hook.Command, hook.Dir)
+	for _, arg := range hook.Args {
+		fmt.Fprintf(h, "arg\x00%s\x00", arg)
+	}
+	env := make([]string, 0, len(hook.Env))
+	for k, v := range hook.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	for _, kv := range env {
+		fmt.Fprintf(h, "env\x00%s\x00", kv)
+	}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(p))
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func outputsExist(outputs []string) bool {
+	for _, output := range outputs {
+		if _, err := os.Stat(output); err != nil {
+			return false
+		}
+	}
+	return true
+}
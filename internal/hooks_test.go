@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.yaml.in/yaml/v4"
+)
+
+func newTestHookRunner(t *testing.T, cfg HooksConfig) (*HookRunner, *bytes.Buffer) {
+	t.Helper()
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(t.TempDir(), "hooks.json")
+	}
+	r, err := NewHookRunner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r.Log = log.New(&out, "", 0)
+	return r, &out
+}
+
+func TestHookConfigYAML(t *testing.T) {
+	data := `
+preBuild:
+  - name: css
+    command: tailwindcss
+    args: [-i, in.css]
+    env: {NODE_ENV: production}
+    timeout: 90s
+    inputs: [templates]
+`
+	var cfg HooksConfig
+	if err := yaml.Load([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	hook := cfg.PreBuild[0]
+	if hook.Timeout != 90*time.Second || hook.Env["NODE_ENV"] != "production" || len(hook.Args) != 2 {
+		t.Errorf("Unexpected hook %+v", hook)
+	}
+}
+
+func TestHookRunner(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Captures Output", func(t *testing.T) {
+		r, out := newTestHookRunner(t, HooksConfig{})
+		hook := HookConfig{Name: "greet", Command: "sh", Args: []string{"-c", "echo hello $WHO"}, Env: map[string]string{"WHO": "hooks"}}
+		if err := r.Run("pre-build", hook); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "[greet] hello hooks") {
+			t.Errorf("Expected captured output, got %q", out.String())
+		}
+	})
+
+	t.Run("Working Directory", func(t *testing.T) {
+		r, _ := newTestHookRunner(t, HooksConfig{})
+		hook := HookConfig{Command: "sh", Args: []string{"-c", "touch marker"}, Dir: dir}
+		if err := r.Run("post-build", hook); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "marker")); err != nil {
+			t.Errorf("Expected hook to run in %s: %v", dir, err)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		r, out := newTestHookRunner(t, HooksConfig{})
+		err := r.Run("pre-build", HookConfig{Name: "broken", Command: "sh", Args: []string{"-c", "echo oops; exit 3"}})
+		if err == nil || !strings.Contains(err.Error(), "pre-build hook broken failed") {
+			t.Errorf("Expected hook failure, got %v", err)
+		}
+		if !strings.Contains(out.String(), "[broken] oops") {
+			t.Error("Expected output of failing hook to be logged")
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r, _ := newTestHookRunner(t, HooksConfig{})
+		err := r.Run("pre-build", HookConfig{Name: "slow", Command: "sleep", Args: []string{"5"}, Timeout: 50 * time.Millisecond})
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Expected timeout, got %v", err)
+		}
+	})
+}
+
+func TestHookRunnerInputsGuard(t *testing.T) {
+	dir := t.TempDir()
+	inputDir := filepath.Join(dir, "src")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(inputDir, "a.css")
+	output := filepath.Join(inputDir, "out.css")
+	counter := filepath.Join(dir, "runs")
+	if err := os.WriteFile(input, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := HooksConfig{
+		StateFile: filepath.Join(dir, "state", "hooks.json"),
+		PreBuild: []HookConfig{{
+			Name:    "css",
+			Command: "sh",
+			Args:    []string{"-c", "echo run >> " + counter + "; date +%N > " + output},
+			Inputs:  []string{inputDir},
+			Outputs: []string{output},
+		}},
+	}
+	runs := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "run")
+	}
+	build := func() {
+		t.Helper()
+		r, _ := newTestHookRunner(t, cfg)
+		if err := r.PreBuild(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	build()
+	build()
+	if got := runs(); got != 1 {
+		t.Fatalf("Expected unchanged inputs to skip the hook, got %d runs", got)
+	}
+
+	if err := os.WriteFile(input, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build()
+	if got := runs(); got != 2 {
+		t.Errorf("Expected changed input to rerun the hook, got %d runs", got)
+	}
+
+	if err := os.Remove(output); err != nil {
+		t.Fatal(err)
+	}
+	build()
+	if got := runs(); got != 3 {
+		t.Errorf("Expected missing output to rerun the hook, got %d runs", got)
+	}
+
+	cfg.PreBuild[0].Args[1] += "; true"
+	build()
+	if got := runs(); got != 4 {
+		t.Errorf("Expected changed arguments to rerun the hook, got %d runs", got)
+	}
+
+	cfg.PreBuild[0].Env = map[string]string{"NODE_ENV": "production"}
+	build()
+	if got := runs(); got != 5 {
+		t.Errorf("Expected changed environment to rerun the hook, got %d runs", got)
+	}
+}
@@ -0,0 +1,385 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // dimensions only; GIFs are published without resizing
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // dimensions only; WebP is published without resizing
+)
+
+// ImageVariant is a single encoded width of a processed image.
+type ImageVariant struct {
+	URL   string
+	Width int
+}
+
+// ProcessedImage describes a local image after resizing, ready to render as <img srcset>.
+type ProcessedImage struct {
+	URL      string
+	Width    int
+	Height   int
+	Variants []ImageVariant
+}
+
+// Srcset formats the variants (and the full-size original) as a srcset attribute value.
+func (p *ProcessedImage) Srcset(prefix string) string {
+	var parts []string
+	for _, v := range p.Variants {
+		parts = append(parts, fmt.Sprintf("%s%s %dw", prefix, v.URL, v.Width))
+	}
+	parts = append(parts, fmt.Sprintf("%s%s %dw", prefix, p.URL, p.Width))
+	return strings.Join(parts, ", ")
+}
+
+// ImageProcessor resizes local post and static images into responsive variants. Encoded
+// files are cached under CacheDir keyed by source content, so unchanged images are only
+// processed once across builds.
+type ImageProcessor struct {
+	Config ImagesConfig
+	Public fs.FS
+
+	mu      sync.Mutex
+	outputs map[string]string // site-relative output path -> cache file
+	static  map[string]*ProcessedImage
+}
+
+// NewImageProcessor creates a processor for cfg, resolving root-relative image paths
+// against the static files in public, which may be nil.
+func NewImageProcessor(cfg ImagesConfig, public fs.FS) *ImageProcessor {
+	if len(cfg.Widths) == 0 {
+		cfg.Widths = []int{480, 960, 1440}
+	}
+	if cfg.Quality == 0 {
+		cfg.Quality = 82
+	}
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = filepath.Join(".cache", "images")
+	}
+	sort.Ints(cfg.Widths)
+	return &ImageProcessor{
+		Config:  cfg,
+		Public:  public,
+		outputs: make(map[string]string),
+		static:  make(map[string]*ProcessedImage),
+	}
+}
+
+// isLocalImage reports whether dest refers to a file in the repository rather than a
+// remote or inline resource.
+func isLocalImage(dest string) bool {
+	lower := strings.ToLower(dest)
+	return dest != "" && !strings.HasPrefix(lower, "http:") && !strings.HasPrefix(lower, "https:") &&
+		!strings.HasPrefix(lower, "//") && !strings.HasPrefix(lower, "data:")
+}
+
+// resizable reports whether ext is a raster format the processor can re-encode.
+func resizable(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// Process resizes the image at srcPath, publishing it and its variants under outDir
+// (a site-relative directory such as "images").
+func (p *ImageProcessor) Process(srcPath, outDir string) (*ProcessedImage, error) {
+	src, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	return p.process(srcPath, src, outDir)
+}
+
+// ProcessFS is like Process for the image name in fsys.
+func (p *ImageProcessor) ProcessFS(fsys fs.FS, name, outDir string) (*ProcessedImage, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.process(name, src, outDir)
+}
+
+// process resizes src, read from srcPath, as described for Process.
+func (p *ImageProcessor) process(srcPath string, src []byte, outDir string) (*ProcessedImage, error) {
+	sum := sha256.Sum256(src)
+	hash := hex.EncodeToString(sum[:])[:10]
+	ext := strings.ToLower(filepath.Ext(srcPath))
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+
+	result := &ProcessedImage{URL: path.Join(outDir, base+"-"+hash+ext)}
+	if err := p.publish(result.URL, hash+ext, func() ([]byte, error) { return src, nil }); err != nil {
+		return nil, err
+	}
+
+	// Vector images have no intrinsic pixel size and are published untouched.
+	if ext == ".svg" {
+		return result, nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", srcPath, err)
+	}
+	result.Width, result.Height = cfg.Width, cfg.Height
+
+	if !resizable(ext) {
+		return result, nil
+	}
+
+	var decoded image.Image
+	for _, w := range p.Config.Widths {
+		if w >= cfg.Width {
+			break
+		}
+		url := path.Join(outDir, fmt.Sprintf("%s-%s-%dw%s", base, hash, w, ext))
+		encode := func() ([]byte, error) {
+			if decoded == nil {
+				if decoded, _, err = image.Decode(bytes.NewReader(src)); err != nil {
+					return nil, fmt.Errorf("failed to decode %s: %w", srcPath, err)
+				}
+			}
+			return p.resize(decoded, format, w)
+		}
+		if err := p.publish(url, fmt.Sprintf("%s-%dw-q%d%s", hash, w, p.Config.Quality, ext), encode); err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, ImageVariant{URL: url, Width: w})
+	}
+	return result, nil
+}
+
+// publish records url as a build output, encoding it into the cache only when missing.
+func (p *ImageProcessor) publish(url, cacheName string, encode func() ([]byte, error)) error {
+	cached := filepath.Join(p.Config.CacheDir, cacheName)
+	if _, err := os.Stat(cached); err != nil {
+		data, err := encode()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(p.Config.CacheDir, 0755); err != nil {
+			return fmt.Errorf("failed to create image cache dir: %w", err)
+		}
+		if err := os.WriteFile(cached, data, 0644); err != nil {
+			return fmt.Errorf("failed to cache image %s: %w", url, err)
+		}
+	}
+
+	p.mu.Lock()
+	p.outputs[url] = cached
+	p.mu.Unlock()
+	return nil
+}
+
+// resize scales img to width, preserving aspect ratio, and re-encodes it as format.
+func (p *ImageProcessor) resize(img image.Image, format string, width int) ([]byte, error) {
+	b := img.Bounds()
+	height := b.Dy() * width / b.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Over, nil)
+
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: p.Config.Quality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ProcessStatic generates variants for every raster image in the public directory so
+// templates can reference them through the srcset function.
+func (p *ImageProcessor) ProcessStatic() error {
+	if p.Public == nil {
+		return nil
+	}
+	if _, err := fs.Stat(p.Public, "."); err != nil {
+		return nil
+	}
+	return fs.WalkDir(p.Public, ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !resizable(path.Ext(rel)) {
+			return err
+		}
+		processed, err := p.ProcessFS(p.Public, rel, path.Join("images", path.Dir(rel)))
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		p.static[rel] = processed
+		p.mu.Unlock()
+		return nil
+	})
+}
+
+// Static returns the processed form of a public asset such as "avatar.png".
+func (p *ImageProcessor) Static(name string) (*ProcessedImage, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	img, ok := p.static[strings.TrimPrefix(name, "/")]
+	return img, ok
+}
+
+// Emit copies every processed image from the cache into distDir in out.
+func (p *ImageProcessor) Emit(out Output, distDir string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for url, cached := range p.outputs {
+		data, err := os.ReadFile(cached)
+		if err == nil {
+			err = out.WriteFile(filepath.Join(distDir, filepath.FromSlash(url)), data)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to emit image %s: %w", url, err)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Goldmark Image Rendering
+// ============================================================================
+
+// figureTransformer lifts a titled image that is alone in its paragraph out of the
+// paragraph, so it can be rendered as a <figure> without nesting it inside a <p>.
+type figureTransformer struct{}
+
+func (figureTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var standalone []*ast.Paragraph
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if para, ok := n.(*ast.Paragraph); ok {
+			if img, ok := para.FirstChild().(*ast.Image); ok && para.ChildCount() == 1 && img.Title != nil {
+				standalone = append(standalone, para)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, para := range standalone {
+		img := para.FirstChild()
+		para.Parent().ReplaceChild(para.Parent(), para, img)
+	}
+}
+
+// imageRenderer renders markdown images with lazy loading, intrinsic dimensions and
+// srcset for local files, wrapping standalone titled images in <figure>.
+type imageRenderer struct {
+	images     *ImageProcessor
+	baseDir    string
+	pathPrefix string
+}
+
+func (r *imageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindImage, r.renderImage)
+}
+
+func (r *imageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	dest := string(n.Destination)
+	src := dest
+
+	var processed *ProcessedImage
+	if r.images != nil && isLocalImage(dest) {
+		var err error
+		if strings.HasPrefix(dest, "/") && r.images.Public != nil {
+			processed, err = r.images.ProcessFS(r.images.Public, path.Clean(strings.TrimPrefix(dest, "/")), "images")
+		} else {
+			processed, err = r.images.Process(filepath.Join(r.baseDir, filepath.FromSlash(dest)), "images")
+		}
+		if err != nil {
+			log.Printf("Warning: keeping image %s unprocessed: %v", dest, err)
+			processed = nil
+		} else {
+			src = r.pathPrefix + processed.URL
+		}
+	}
+	if processed == nil && strings.HasPrefix(dest, "/") && !strings.HasPrefix(dest, "//") {
+		// Site paths resolve from the page like relURL, so they survive a path prefix.
+		src = r.pathPrefix + strings.TrimPrefix(dest, "/")
+	}
+
+	figure := n.Title != nil && n.Parent().Kind() != ast.KindParagraph
+	if figure {
+		_, _ = w.WriteString("<figure>")
+	}
+
+	_, _ = w.WriteString(`<img src="`)
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(src), true)))
+	_, _ = w.WriteString(`" alt="`)
+	_, _ = w.Write(util.EscapeHTML(imageAltText(n, source)))
+	_ = w.WriteByte('"')
+	if n.Title != nil && !figure {
+		_, _ = w.WriteString(` title="`)
+		_, _ = w.Write(util.EscapeHTML(n.Title))
+		_ = w.WriteByte('"')
+	}
+	if processed != nil && processed.Width > 0 {
+		fmt.Fprintf(w, ` width="%d" height="%d"`, processed.Width, processed.Height)
+		if len(processed.Variants) > 0 {
+			fmt.Fprintf(w, ` srcset="%s" sizes="(min-width: 768px) 768px, 100vw"`, util.EscapeHTML([]byte(processed.Srcset(r.pathPrefix))))
+		}
+	}
+	_, _ = w.WriteString(` loading="lazy" decoding="async">`)
+
+	if figure {
+		_, _ = w.WriteString("<figcaption>")
+		_, _ = w.Write(util.EscapeHTML(n.Title))
+		_, _ = w.WriteString("</figcaption></figure>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// imageAltText flattens the inline children of an image into plain alt text.
+func imageAltText(n ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+		case *ast.String:
+			buf.Write(t.Value)
+		default:
+			buf.Write(imageAltText(c, source))
+		}
+	}
+	return buf.Bytes()
+}
+
+// imageExtension wires the figure transformer and image renderer into goldmark.
+type imageExtension struct {
+	renderer *imageRenderer
+}
+
+func (e *imageExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(figureTransformer{}, 100)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(e.renderer, 100)))
+}
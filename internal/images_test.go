@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPNG writes a solid w x h PNG to path.
+func writeTestPNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{0x80, 0x40, 0xff, 0xff})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsLocalImage(t *testing.T) {
+	tests := []struct {
+		dest string
+		want bool
+	}{
+		{"images/a.png", true},
+		{"/avatar.png", true},
+		{"https://github.com/a.png", false},
+		{"HTTP://example.com/a.png", false},
+		{"//cdn.example.com/a.png", false},
+		{"data:image/png;base64,AAAA", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isLocalImage(tt.dest); got != tt.want {
+			t.Errorf("isLocalImage(%q) = %v, want %v", tt.dest, got, tt.want)
+		}
+	}
+}
+
+func TestImageProcessor(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "photo.png")
+	writeTestPNG(t, src, 600, 300)
+
+	cacheDir := filepath.Join(tmpDir, "cache")
+	proc := NewImageProcessor(ImagesConfig{Widths: []int{800, 200}, CacheDir: cacheDir}, nil)
+
+	img, err := proc.Process(src, "images")
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if img.Width != 600 || img.Height != 300 {
+		t.Errorf("Expected 600x300, got %dx%d", img.Width, img.Height)
+	}
+	if len(img.Variants) != 1 || img.Variants[0].Width != 200 {
+		t.Fatalf("Expected a single 200w variant (800w exceeds the original), got %+v", img.Variants)
+	}
+	if !strings.HasPrefix(img.URL, "images/photo-") {
+		t.Errorf("Unexpected URL %q", img.URL)
+	}
+
+	distDir := filepath.Join(tmpDir, "dist")
+	if err := proc.Emit(DiskOutput{}, distDir); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	f, err := os.Open(filepath.Join(distDir, filepath.FromSlash(img.Variants[0].URL)))
+	if err != nil {
+		t.Fatalf("Variant not emitted: %v", err)
+	}
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 200 || cfg.Height != 100 {
+		t.Errorf("Expected 200x100 variant, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// A second processor reuses the cached variant instead of re-encoding it.
+	cached, _ := filepath.Glob(filepath.Join(cacheDir, "*-200w-*.png"))
+	if len(cached) != 1 {
+		t.Fatalf("Expected cached variant, got %v", cached)
+	}
+	if err := os.WriteFile(cached[0], []byte("cached"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	again := NewImageProcessor(ImagesConfig{Widths: []int{200}, CacheDir: cacheDir}, nil)
+	if _, err := again.Process(src, "images"); err != nil {
+		t.Fatal(err)
+	}
+	if err := again.Emit(DiskOutput{}, distDir); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(filepath.Join(distDir, filepath.FromSlash(img.Variants[0].URL)))
+	if string(got) != "cached" {
+		t.Error("Expected cached variant to be reused")
+	}
+}
+
+func TestParsePostImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestPNG(t, filepath.Join(tmpDir, "diagram.png"), 1000, 500)
+	publicDir := filepath.Join(tmpDir, "static")
+	if err := os.MkdirAll(publicDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestPNG(t, filepath.Join(publicDir, "logo.png"), 100, 100)
+
+	post := `---
+title: "Images"
+date: 2026-01-01T00:00:00Z
+---
+![A diagram](diagram.png "The architecture")
+
+Inline ![logo](/logo.png) and ![remote](https://example.com/r.png).
+`
+	path := filepath.Join(tmpDir, "images.md")
+	if err := os.WriteFile(path, []byte(post), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proc := NewImageProcessor(ImagesConfig{Widths: []int{480}, CacheDir: filepath.Join(tmpDir, "cache")}, os.DirFS(publicDir))
+	p, err := ParsePost(path, WithImageProcessor(proc))
+	if err != nil {
+		t.Fatalf("ParsePost() error = %v", err)
+	}
+
+	checks := []string{
+		`<figure><img src="../images/diagram-`,
+		`width="1000" height="500"`,
+		`-480w.png 480w`,
+		`<figcaption>The architecture</figcaption></figure>`,
+		`width="100" height="100"`,
+		`<img src="https://example.com/r.png" alt="remote" loading="lazy" decoding="async">`,
+	}
+	for _, want := range checks {
+		if !strings.Contains(p.Content, want) {
+			t.Errorf("Expected content to contain %q, got:\n%s", want, p.Content)
+		}
+	}
+	if strings.Contains(p.Content, "<p><figure>") {
+		t.Error("Figure must not be nested inside a paragraph")
+	}
+
+	t.Run("Without Processor", func(t *testing.T) {
+		p, err := ParsePost(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(p.Content, `<img src="diagram.png" alt="A diagram" loading="lazy" decoding="async">`) {
+			t.Errorf("Expected untouched lazy image, got:\n%s", p.Content)
+		}
+	})
+
+	t.Run("Unreadable Local Images", func(t *testing.T) {
+		missing := filepath.Join(tmpDir, "missing.md")
+		content := "---\ntitle: x\n---\n![gone](nope.png) ![raw](diagram.png?raw=true) ![encoded](my%20diagram.png)\n"
+		if err := os.WriteFile(missing, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := ParsePost(missing, WithImageProcessor(proc))
+		if err != nil {
+			t.Fatalf("Expected unreadable images not to fail the post, got %v", err)
+		}
+		for _, want := range []string{
+			`<img src="nope.png" alt="gone" loading="lazy" decoding="async">`,
+			`<img src="diagram.png?raw=true" alt="raw" loading="lazy" decoding="async">`,
+			`<img src="my%20diagram.png" alt="encoded" loading="lazy" decoding="async">`,
+		} {
+			if !strings.Contains(p.Content, want) {
+				t.Errorf("Expected the original destination in %q, got:\n%s", want, p.Content)
+			}
+		}
+	})
+
+	t.Run("Unprocessed Site Paths", func(t *testing.T) {
+		sitePath := filepath.Join(tmpDir, "site-path.md")
+		content := "---\ntitle: x\n---\n![stored](/remote-images/abc.png) ![cdn](//cdn.example.com/a.png)\n"
+		if err := os.WriteFile(sitePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := ParsePost(sitePath, WithPathPrefix("../../"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`<img src="../../remote-images/abc.png"`, `<img src="//cdn.example.com/a.png"`} {
+			if !strings.Contains(p.Content, want) {
+				t.Errorf("Expected %q, got:\n%s", want, p.Content)
+			}
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// BuildInspector records what a build rendered, so the dev server can show the
+// template and PageData behind every page and how long each step took.
+type BuildInspector struct {
+	mu      sync.Mutex
+	pages   map[string]InspectedPage
+	timings []StepTiming
+}
+
+// InspectedPage is a page passed to RenderPage along with its final PageData.
+type InspectedPage struct {
+	Path     string   `json:"path"`
+	Template string   `json:"template"`
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+	Data     PageData `json:"-"`
+}
+
+// StepTiming is the duration of one pipeline phase or generator step.
+type StepTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON reports the duration both readably and in milliseconds.
+func (s StepTiming) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name     string  `json:"name"`
+		Duration string  `json:"duration"`
+		Millis   float64 `json:"ms"`
+	}{s.Name, s.Duration.Round(time.Microsecond).String(), float64(s.Duration.Microseconds()) / 1000})
+}
+
+// NewBuildInspector returns an empty inspector.
+func NewBuildInspector() *BuildInspector {
+	return &BuildInspector{pages: make(map[string]InspectedPage)}
+}
+
+// recordPage stores a rendered page. It is a no-op on a nil inspector.
+func (i *BuildInspector) recordPage(tmplPath string, data PageData) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.pages[data.Path] = InspectedPage{
+		Path:     data.Path,
+		Template: tmplPath,
+		Title:    data.Title,
+		Type:     pageType(data),
+		Data:     data,
+	}
+}
+
+// lap records the time since *start under name and restarts the clock. It is a
+// no-op on a nil inspector.
+func (i *BuildInspector) lap(name string, start *time.Time) {
+	if i == nil {
+		return
+	}
+	now := time.Now()
+	i.mu.Lock()
+	i.timings = append(i.timings, StepTiming{Name: name, Duration: now.Sub(*start)})
+	i.mu.Unlock()
+	*start = now
+}
+
+// Pages returns the recorded pages sorted by path.
+func (i *BuildInspector) Pages() []InspectedPage {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	pages := make([]InspectedPage, 0, len(i.pages))
+	for _, p := range i.pages {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(a, b int) bool { return pages[a].Path < pages[b].Path })
+	return pages
+}
+
+// Page looks up a page by site-relative path, accepting clean URLs the way the
+// static server resolves them.
+func (i *BuildInspector) Page(p string) (InspectedPage, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	rel := strings.Trim(path.Clean("/"+p), "/")
+	for _, candidate := range []string{rel, rel + ".html", path.Join(rel, "index.html")} {
+		if page, ok := i.pages[candidate]; ok {
+			return page, true
+		}
+	}
+	return InspectedPage{}, false
+}
+
+// Timings returns the recorded step durations in the order they ran.
+func (i *BuildInspector) Timings() []StepTiming {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]StepTiming(nil), i.timings...)
+}
+
+// PostOutline describes how a post's source parses: its frontmatter and one line
+// per Markdown AST node, indented by depth.
+type PostOutline struct {
+	Frontmatter Frontmatter `json:"frontmatter"`
+	Outline     []string    `json:"outline"`
+}
+
+// OutlinePost parses the Markdown file at path with the same extensions as
+// ParsePost and returns its frontmatter and AST outline.
+func OutlinePost(path string) (*PostOutline, error) {
+	fm, body, err := readPostSource(path)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("%s has no frontmatter", path)
+	}
+
+	md := newMarkdown(parseConfig{}, filepath.Dir(path), "../")
+	doc := md.Parser().Parse(text.NewReader(body))
+
+	outline := &PostOutline{Frontmatter: fm}
+	depth := 0
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			depth--
+			return ast.WalkContinue, nil
+		}
+		if n.Kind() != ast.KindText {
+			outline.Outline = append(outline.Outline, strings.Repeat("  ", depth)+describeNode(n, body))
+		}
+		depth++
+		return ast.WalkContinue, nil
+	})
+	return outline, err
+}
+
+// describeNode names an AST node with the details that matter when debugging
+// rendering: heading levels and text, link and image targets, code languages.
+func describeNode(n ast.Node, source []byte) string {
+	kind := n.Kind().String()
+	switch v := n.(type) {
+	case *ast.Heading:
+		return fmt.Sprintf("%s[%d] %q", kind, v.Level, nodeText(n, source))
+	case *ast.Link:
+		return fmt.Sprintf("%s %s", kind, v.Destination)
+	case *ast.Image:
+		return fmt.Sprintf("%s %s", kind, v.Destination)
+	case *ast.FencedCodeBlock:
+		if lang := v.Language(source); lang != nil {
+			return fmt.Sprintf("%s %s", kind, lang)
+		}
+	case *ast.List:
+		if v.IsOrdered() {
+			return kind + " ordered"
+		}
+	}
+	return kind
+}
+
+// nodeText concatenates the text segments below n.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			b.Write(t.Segment.Value(source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// Handler serves the inspector under prefix as JSON:
+//
+//	prefix            the available endpoints
+//	prefix+pages      every rendered page with its template
+//	prefix+page?path= the PageData passed to one page
+//	prefix+post?slug= a post's frontmatter and Markdown AST outline, read from the
+//	                  first of contentDirs holding slug.md
+//	prefix+timings    the durations of the last build's steps
+func (i *BuildInspector) Handler(prefix string, contentDirs []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix {
+			http.NotFound(w, r)
+			return
+		}
+		writeInspectJSON(w, map[string]string{
+			"pages":   prefix + "pages",
+			"page":    prefix + "page?path=index.html",
+			"post":    prefix + "post?slug=<slug>",
+			"timings": prefix + "timings",
+		})
+	})
+	mux.HandleFunc(prefix+"pages", func(w http.ResponseWriter, r *http.Request) {
+		writeInspectJSON(w, i.Pages())
+	})
+	mux.HandleFunc(prefix+"page", func(w http.ResponseWriter, r *http.Request) {
+		page, ok := i.Page(r.URL.Query().Get("path"))
+		if !ok {
+			http.Error(w, "no page rendered at that path", http.StatusNotFound)
+			return
+		}
+		writeInspectJSON(w, page.Data)
+	})
+	mux.HandleFunc(prefix+"post", func(w http.ResponseWriter, r *http.Request) {
+		slug := r.URL.Query().Get("slug")
+		if slug == "" || strings.ContainsAny(slug, `/\`) || strings.HasPrefix(slug, ".") {
+			http.Error(w, "invalid slug", http.StatusBadRequest)
+			return
+		}
+		var path string
+		for _, dir := range contentDirs {
+			if _, err := os.Stat(filepath.Join(dir, slug+".md")); err == nil {
+				path = filepath.Join(dir, slug+".md")
+				break
+			}
+		}
+		if path == "" {
+			http.Error(w, fmt.Sprintf("no %s.md in %s", slug, strings.Join(contentDirs, ", ")), http.StatusNotFound)
+			return
+		}
+		outline, err := OutlinePost(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeInspectJSON(w, outline)
+	})
+	mux.HandleFunc(prefix+"timings", func(w http.ResponseWriter, r *http.Request) {
+		timings := i.Timings()
+		var total time.Duration
+		for _, t := range timings {
+			if !strings.Contains(t.Name, "/") {
+				total += t.Duration
+			}
+		}
+		writeInspectJSON(w, map[string]interface{}{
+			"total": total.Round(time.Millisecond).String(),
+			"steps": timings,
+		})
+	})
+	return localOnly(mux)
+}
+
+func writeInspectJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
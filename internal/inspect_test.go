@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildInspector(t *testing.T) {
+	inspector := NewBuildInspector()
+	_, err := RunPipeline(
+		filepath.Join(t.TempDir(), "dist"),
+		filepath.Join(snapshotFixtureDir, "contents"),
+		"templates",
+		filepath.Join(snapshotFixtureDir, "blog"),
+		filepath.Join(snapshotFixtureDir, "static"),
+		WithInspector(inspector),
+	)
+	if err != nil {
+		t.Fatalf("RunPipeline() error = %v", err)
+	}
+
+	templates := make(map[string]string)
+	for _, p := range inspector.Pages() {
+		templates[p.Path] = p.Template
+	}
+	for path, want := range map[string]string{
+		"index.html":           "index.html",
+		"blog.html":            "blog.html",
+		"tags/go.html":         "blog.html",
+		"blog/first-post.html": "post.html",
+	} {
+		if templates[path] != want {
+			t.Errorf("Template for %s = %q, want %q", path, templates[path], want)
+		}
+	}
+
+	page, ok := inspector.Page("/blog/first-post")
+	if !ok || page.Data.Post == nil || page.Data.Post.Title != "First Post" || page.Data.PathPrefix != "../" {
+		t.Errorf("Expected clean URL lookup of the first post's PageData, got %+v (found %v)", page.Data.Post, ok)
+	}
+	if _, ok := inspector.Page("missing.html"); ok {
+		t.Error("Expected no page for missing.html")
+	}
+
+	names := make(map[string]bool)
+	for _, timing := range inspector.Timings() {
+		names[timing.Name] = true
+	}
+	for _, want := range []string{"pre-build hooks", "content", "build", "build/post pages", "build/sitemap", "swap"} {
+		if !names[want] {
+			t.Errorf("Expected a timing for %q, got %v", want, names)
+		}
+	}
+}
+
+func TestOutlinePost(t *testing.T) {
+	outline, err := OutlinePost(filepath.Join(snapshotFixtureDir, "blog", "first-post.md"))
+	if err != nil {
+		t.Fatalf("OutlinePost() error = %v", err)
+	}
+	if outline.Frontmatter.Title != "First Post" {
+		t.Errorf("Frontmatter title = %q", outline.Frontmatter.Title)
+	}
+	want := []string{
+		"Document",
+		`  Heading[2] "Hello"`,
+		"  Paragraph",
+		"    CodeSpan",
+		"    Link https://example.com",
+		"  FencedCodeBlock go",
+	}
+	if !reflect.DeepEqual(outline.Outline, want) {
+		t.Errorf("Outline =\n%s\nwant\n%s", strings.Join(outline.Outline, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := OutlinePost(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("Expected an error for a missing post")
+	}
+}
+
+func TestBuildInspectorHandler(t *testing.T) {
+	inspector := NewBuildInspector()
+	inspector.recordPage("post.html", PageData{Path: "blog/first-post.html", Title: "First Post | Site", Post: &Post{Slug: "first-post"}})
+	notesDir := writeTree(t, map[string]string{
+		"first-note.md": "---\ntitle: First Note\ndate: 2024-01-02\n---\n\n## Jotted\n",
+	})
+	handler := inspector.Handler("/__mehub/", []string{filepath.Join(snapshotFixtureDir, "blog"), notesDir})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/__mehub/", 200, `"timings": "/__mehub/timings"`},
+		{"/__mehub/pages", 200, `"template": "post.html"`},
+		{"/__mehub/page?path=blog/first-post.html", 200, `"Slug": "first-post"`},
+		{"/__mehub/page?path=nope.html", 404, "no page"},
+		{"/__mehub/post?slug=first-post", 200, `Heading[2] \"Hello\"`},
+		{"/__mehub/post?slug=first-note", 200, `Heading[2] \"Jotted\"`},
+		{"/__mehub/post?slug=../first-post", 400, "invalid slug"},
+		{"/__mehub/post?slug=missing", 404, ""},
+		{"/__mehub/timings", 200, `"steps"`},
+		{"/__mehub/other", 404, ""},
+	}
+	remote := httptest.NewRecorder()
+	handler.ServeHTTP(remote, httptest.NewRequest(http.MethodGet, "/__mehub/pages", nil))
+	if remote.Code != http.StatusForbidden {
+		t.Errorf("Expected remote clients to be rejected, got %d", remote.Code)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = "127.0.0.1:50000"
+			req.Host = "localhost:8080"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("Body = %s, want it to contain %s", rec.Body.String(), tt.wantBody)
+			}
+			if rec.Code == 200 && !json.Valid(rec.Body.Bytes()) {
+				t.Errorf("Expected JSON, got %s", rec.Body.String())
+			}
+		})
+	}
+}
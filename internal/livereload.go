@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReloadEvent is a typed message sent to dev server pages over the /dev-reload
+// event stream.
+type ReloadEvent struct {
+	Name string
+	Data interface{}
+}
+
+// CSSChange carries the current names of rebuilt stylesheets, keyed by their
+// unfingerprinted paths, so pages can swap their <link> tags in place.
+type CSSChange struct {
+	Assets map[string]string `json:"assets"`
+}
+
+// PageChange lists the site-relative pages whose content changed.
+type PageChange struct {
+	Paths []string `json:"paths"`
+}
+
+// reloadIgnoredExts are outputs that pages do not display directly.
+var reloadIgnoredExts = map[string]bool{".json": true, ".xml": true, ".txt": true}
+
+// ReloadEvents turns the changed outputs of a rebuild, as returned by
+// ChangedDigests, into the events for open pages: css-changed for stylesheets,
+// page-changed for HTML, and a single reload when a script, image or other asset
+// changed that pages cannot pick up on their own.
+func ReloadEvents(changed map[string]string) []ReloadEvent {
+	css := make(map[string]string)
+	var pages []string
+	for key, rel := range changed {
+		switch ext := path.Ext(key); {
+		case ext == ".css":
+			css[key] = rel
+		case ext == ".html":
+			pages = append(pages, key)
+		case reloadIgnoredExts[ext]:
+		default:
+			return []ReloadEvent{{Name: "reload", Data: struct{}{}}}
+		}
+	}
+
+	var events []ReloadEvent
+	if len(css) > 0 {
+		events = append(events, ReloadEvent{Name: "css-changed", Data: CSSChange{Assets: css}})
+	}
+	if len(pages) > 0 {
+		sort.Strings(pages)
+		events = append(events, ReloadEvent{Name: "page-changed", Data: PageChange{Paths: pages}})
+	}
+	return events
+}
+
+// WriteTo writes the event in text/event-stream format.
+func (e ReloadEvent) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s event: %w", e.Name, err)
+	}
+	n, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data)
+	return int64(n), err
+}
+
+// InjectBeforeBodyEnd inserts snippet before the closing </body> tag. Minified
+// pages omit the optional </body> and </html> tags, so it falls back to </html>
+// and then to the end of the document, where browsers still place it in the body.
+func InjectBeforeBodyEnd(html, snippet string) string {
+	lower := strings.ToLower(html)
+	for _, tag := range []string{"</body>", "</html>"} {
+		if i := strings.LastIndex(lower, tag); i >= 0 {
+			return html[:i] + snippet + html[i:]
+		}
+	}
+	return html + snippet
+}
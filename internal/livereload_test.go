@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReloadEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed map[string]string
+		want    []ReloadEvent
+	}{
+		{
+			name:    "Nothing Changed",
+			changed: map[string]string{},
+			want:    nil,
+		},
+		{
+			name:    "Stylesheet And Pages",
+			changed: map[string]string{"styles.css": "styles.1a2b3c4d.css", "blog/b.html": "blog/b.html", "index.html": "index.html", "sitemap.xml": "sitemap.xml"},
+			want: []ReloadEvent{
+				{Name: "css-changed", Data: CSSChange{Assets: map[string]string{"styles.css": "styles.1a2b3c4d.css"}}},
+				{Name: "page-changed", Data: PageChange{Paths: []string{"blog/b.html", "index.html"}}},
+			},
+		},
+		{
+			name:    "Data Files Only",
+			changed: map[string]string{"search-index.json": "search-index.json", "rss.xml": "rss.xml"},
+			want:    nil,
+		},
+		{
+			name:    "Image Forces Reload",
+			changed: map[string]string{"index.html": "index.html", "avatar.png": "avatar.png"},
+			want:    []ReloadEvent{{Name: "reload", Data: struct{}{}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReloadEvents(tt.changed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReloadEvents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadEventWriteTo(t *testing.T) {
+	var buf bytes.Buffer
+	e := ReloadEvent{Name: "page-changed", Data: PageChange{Paths: []string{"index.html"}}}
+	if _, err := e.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "event: page-changed\ndata: {\"paths\":[\"index.html\"]}\n\n"
+	if buf.String() != want {
+		t.Errorf("WriteTo() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestInjectBeforeBodyEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"Body Tag", "<body><p>x</p></body></html>", "<body><p>x</p><s></body></html>"},
+		{"Uppercase Body Tag", "<BODY>x</BODY>", "<BODY>x<s></BODY>"},
+		{"Last Body Tag Wins", "<p>&lt;/body></p></body>", "<p>&lt;/body></p><s></body>"},
+		{"Html Tag Only", "<p>x</p></html>", "<p>x</p><s></html>"},
+		{"Minified Without End Tags", "<!doctype html><p>x", "<!doctype html><p>x<s>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InjectBeforeBodyEnd(tt.html, "<s>"); got != tt.want {
+				t.Errorf("InjectBeforeBodyEnd() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
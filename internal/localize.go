@@ -0,0 +1,396 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// maxRemoteImageSize caps a single download so a misbehaving host cannot exhaust disk.
+const maxRemoteImageSize = 25 << 20
+
+// imageExtensions maps image MIME types to the file extension used in the local store.
+var imageExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/avif":    ".avif",
+}
+
+// LockEntry records the content hash and stored file of one localized image.
+type LockEntry struct {
+	SHA256 string `json:"sha256"`
+	File   string `json:"file"`
+}
+
+// ImageLock is the on-disk lockfile mapping remote image URLs to local copies.
+type ImageLock struct {
+	Version int                  `json:"version"`
+	Images  map[string]LockEntry `json:"images"`
+}
+
+// ImageLocalizer downloads remote images into a content-addressed store, keeping a
+// lockfile so each URL is only fetched once.
+type ImageLocalizer struct {
+	Config RemoteImagesConfig
+	Client *http.Client
+
+	lock  ImageLock
+	dirty bool
+	used  map[string]bool
+}
+
+// NewImageLocalizer loads the lockfile for cfg, starting an empty one if none exists.
+func NewImageLocalizer(cfg RemoteImagesConfig) (*ImageLocalizer, error) {
+	if cfg.StoreDir == "" {
+		cfg.StoreDir = "remote-images"
+	}
+	if cfg.Lockfile == "" {
+		cfg.Lockfile = "remote-images.lock.json"
+	}
+	if cfg.URLPath == "" {
+		cfg.URLPath = "remote-images"
+	}
+
+	l := &ImageLocalizer{
+		Config: cfg,
+		Client: &http.Client{Timeout: 30 * time.Second},
+		lock:   ImageLock{Version: 1, Images: make(map[string]LockEntry)},
+		used:   make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(cfg.Lockfile)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image lockfile: %w", err)
+	}
+	if err := json.Unmarshal(data, &l.lock); err != nil {
+		return nil, fmt.Errorf("failed to parse image lockfile %s: %w", cfg.Lockfile, err)
+	}
+	if l.lock.Images == nil {
+		l.lock.Images = make(map[string]LockEntry)
+	}
+	return l, nil
+}
+
+// Localize returns the stored file name for url, downloading it when the lockfile has
+// no entry or the stored copy is missing.
+func (l *ImageLocalizer) Localize(url string) (string, error) {
+	if entry, ok := l.lock.Images[url]; ok {
+		if _, err := os.Stat(filepath.Join(l.Config.StoreDir, entry.File)); err == nil {
+			l.used[entry.File] = true
+			return entry.File, nil
+		}
+	}
+
+	resp, err := l.Client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+	ext, ok := imageExtensions[mediaType]
+	if !ok {
+		ext = strings.ToLower(path.Ext(resp.Request.URL.Path))
+		if !strings.HasPrefix(mediaType, "image/") && ext == "" {
+			return "", fmt.Errorf("%s is not an image (content type %q)", url, mediaType)
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteImageSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(body) > maxRemoteImageSize {
+		return "", fmt.Errorf("%s exceeds %d bytes", url, maxRemoteImageSize)
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	file := hash[:16] + ext
+
+	if err := os.MkdirAll(l.Config.StoreDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create image store: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(l.Config.StoreDir, file), body, 0644); err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", url, err)
+	}
+
+	l.lock.Images[url] = LockEntry{SHA256: hash, File: file}
+	l.dirty = true
+	l.used[file] = true
+	return file, nil
+}
+
+// SaveLock writes the lockfile if any entry changed since it was loaded.
+func (l *ImageLocalizer) SaveLock() error {
+	if !l.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(l.lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal image lockfile: %w", err)
+	}
+	if err := os.WriteFile(l.Config.Lockfile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write image lockfile: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
+
+// LocalURL returns the site-relative URL of a stored file.
+func (l *ImageLocalizer) LocalURL(file string) string {
+	return strings.Trim(l.Config.URLPath, "/") + "/" + file
+}
+
+var imgSrcPattern = regexp.MustCompile(`(<img\b[^>]*?\bsrc=")(https?://[^"]+)(")`)
+
+// RewriteHTML points every remote <img src> in rendered HTML at its localized copy.
+// The rendered src is HTML-escaped, so it is unescaped before the lockfile lookup.
+// Images that cannot be fetched keep their remote URL and are reported as warnings.
+func (l *ImageLocalizer) RewriteHTML(content, pathPrefix string) string {
+	return imgSrcPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := imgSrcPattern.FindStringSubmatch(match)
+		file, err := l.Localize(html.UnescapeString(parts[2]))
+		if err != nil {
+			log.Printf("Warning: keeping remote image: %v", err)
+			return match
+		}
+		return parts[1] + pathPrefix + l.LocalURL(file) + parts[3]
+	})
+}
+
+// Emit copies every stored image referenced during this build into distDir in out.
+func (l *ImageLocalizer) Emit(out Output, distDir string) error {
+	outDir := filepath.Join(distDir, filepath.FromSlash(l.Config.URLPath))
+	for file := range l.used {
+		data, err := os.ReadFile(filepath.Join(l.Config.StoreDir, file))
+		if err == nil {
+			err = out.WriteFile(filepath.Join(outDir, file), data)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to emit localized image %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// useStoredImages marks the stored files that rendered HTML already references under
+// pathPrefix, such as those written into markdown by localize-images -rewrite.
+func (l *ImageLocalizer) useStoredImages(content, pathPrefix string) {
+	dir := pathPrefix + strings.Trim(l.Config.URLPath, "/") + "/"
+	for _, m := range localImgSrcPattern.FindAllStringSubmatch(content, -1) {
+		file, ok := strings.CutPrefix(html.UnescapeString(m[1]), dir)
+		if !ok || strings.Contains(file, "/") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(l.Config.StoreDir, file)); err == nil {
+			l.used[file] = true
+		}
+	}
+}
+
+var localImgSrcPattern = regexp.MustCompile(`<img\b[^>]*?\bsrc="([^"]+)"`)
+
+// LocalizeRenderedImages publishes the stored images used by the rendered post bodies
+// into distDir in out. With cfg.Localize, remote images are first rewritten to local
+// copies and new downloads recorded in the lockfile; stored images that the markdown
+// references directly are published either way. pathPrefix is the relative path from
+// a post back to the site root, as passed to WithPathPrefix.
+func LocalizeRenderedImages(out Output, posts []Post, cfg RemoteImagesConfig, distDir, pathPrefix string) error {
+	l, err := NewImageLocalizer(cfg)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if cfg.Localize {
+			posts[i].Content = l.RewriteHTML(posts[i].Content, pathPrefix)
+		}
+		l.useStoredImages(posts[i].Content, pathPrefix)
+	}
+	if err := l.Emit(out, distDir); err != nil {
+		return err
+	}
+	return l.SaveLock()
+}
+
+// RemoteImageURLs lists the remote image destinations referenced by markdown, ignoring
+// anything inside code blocks.
+func RemoteImageURLs(markdown []byte) []string {
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(markdown))
+
+	var urls []string
+	seen := make(map[string]bool)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			dest := string(img.Destination)
+			if !isLocalImage(dest) && strings.HasPrefix(strings.ToLower(dest), "http") && !seen[dest] {
+				seen[dest] = true
+				urls = append(urls, dest)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return urls
+}
+
+// rewriteImageDestinations replaces the destination of every inline image in markdown
+// for which replace returns true. Ranges come from the goldmark AST, so links and
+// code that mention the same URL are left alone.
+func rewriteImageDestinations(markdown []byte, replace func(dest string) (string, bool)) []byte {
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(markdown))
+
+	type edit struct {
+		start, stop int
+		dest        string
+	}
+	var edits []edit
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := n.(*ast.Image)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		start, ok := imageDestinationStart(markdown, img.Pos())
+		if !ok || !bytes.HasPrefix(markdown[start:], img.Destination) {
+			return ast.WalkContinue, nil
+		}
+		if dest, ok := replace(string(img.Destination)); ok {
+			edits = append(edits, edit{start, start + len(img.Destination), dest})
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		out.Write(markdown[last:e.start])
+		out.WriteString(e.dest)
+		last = e.stop
+	}
+	out.Write(markdown[last:])
+	return out.Bytes()
+}
+
+// imageDestinationStart returns the offset of the destination of the inline image
+// whose "![" opener is at pos. Reference images have no inline destination.
+func imageDestinationStart(source []byte, pos int) (int, bool) {
+	if pos < 0 || !bytes.HasPrefix(source[pos:], []byte("![")) {
+		return 0, false
+	}
+	depth := 0
+	for i := pos + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth > 0 {
+				continue
+			}
+			j := i + 1
+			if j >= len(source) || source[j] != '(' {
+				return 0, false
+			}
+			for j++; j < len(source) && (source[j] == ' ' || source[j] == '\t' || source[j] == '\n'); j++ {
+			}
+			if j < len(source) && source[j] == '<' {
+				j++
+			}
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// LocalizeResult summarizes a localize-images run.
+type LocalizeResult struct {
+	Images    int
+	Rewritten int
+	Failed    []string
+}
+
+// LocalizePostImages downloads every remote image referenced by the markdown files in
+// contentDir. With rewrite, the image destinations are updated to the site paths the
+// stored copies are published at, which builds resolve against each page's path prefix.
+func LocalizePostImages(contentDir string, cfg RemoteImagesConfig, rewrite bool) (*LocalizeResult, error) {
+	l, err := NewImageLocalizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(contentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LocalizeResult{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".md") {
+			continue
+		}
+		mdPath := filepath.Join(contentDir, file.Name())
+		source, err := os.ReadFile(mdPath)
+		if err != nil {
+			return nil, err
+		}
+
+		stored := make(map[string]string)
+		for _, url := range RemoteImageURLs(source) {
+			file, err := l.Localize(url)
+			if err != nil {
+				result.Failed = append(result.Failed, err.Error())
+				continue
+			}
+			result.Images++
+			stored[url] = file
+		}
+
+		updated := string(source)
+		if rewrite {
+			updated = string(rewriteImageDestinations(source, func(dest string) (string, bool) {
+				file, ok := stored[dest]
+				return "/" + l.LocalURL(file), ok
+			}))
+		}
+
+		if updated != string(source) {
+			if err := os.WriteFile(mdPath, []byte(updated), 0644); err != nil {
+				return nil, err
+			}
+			result.Rewritten++
+		}
+	}
+
+	if err := l.SaveLock(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
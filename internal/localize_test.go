@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newImageServer stands in for a remote image host, counting requests per path.
+func newImageServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a.png", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png-bytes"))
+	})
+	mux.HandleFunc("/attachment", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg-bytes"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func testRemoteImagesConfig(t *testing.T) RemoteImagesConfig {
+	tmpDir := t.TempDir()
+	return RemoteImagesConfig{
+		StoreDir: filepath.Join(tmpDir, "store"),
+		Lockfile: filepath.Join(tmpDir, "images.lock.json"),
+		URLPath:  "remote-images",
+	}
+}
+
+func TestImageLocalizer(t *testing.T) {
+	srv, hits := newImageServer(t)
+	cfg := testRemoteImagesConfig(t)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantExt string
+		wantErr bool
+	}{
+		{name: "PNG By Content Type", url: srv.URL + "/a.png", wantExt: ".png"},
+		{name: "Extensionless Attachment", url: srv.URL + "/attachment", wantExt: ".jpg"},
+		{name: "Not An Image", url: srv.URL + "/page", wantErr: true},
+		{name: "Not Found", url: srv.URL + "/missing.png", wantErr: true},
+	}
+
+	l, err := NewImageLocalizer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := l.Localize(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Localize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if filepath.Ext(file) != tt.wantExt {
+				t.Errorf("Expected extension %s, got %s", tt.wantExt, file)
+			}
+			if _, err := os.Stat(filepath.Join(cfg.StoreDir, file)); err != nil {
+				t.Errorf("Stored file missing: %v", err)
+			}
+		})
+	}
+
+	if err := l.SaveLock(); err != nil {
+		t.Fatal(err)
+	}
+	var lock ImageLock
+	data, err := os.ReadFile(cfg.Lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Images) != 2 {
+		t.Errorf("Expected 2 locked images, got %v", lock.Images)
+	}
+
+	// A fresh localizer resolves locked URLs from the store without any network access.
+	before := hits.Load()
+	again, err := NewImageLocalizer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Localize(srv.URL + "/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if hits.Load() != before {
+		t.Error("Expected locked image to be served from the store")
+	}
+}
+
+func TestRewriteHTML(t *testing.T) {
+	srv, hits := newImageServer(t)
+	cfg := testRemoteImagesConfig(t)
+	l, err := NewImageLocalizer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := `<p><img src="` + srv.URL + `/a.png" alt="a"></p><img src="` + srv.URL + `/missing.png" alt="gone"><pre>&lt;img src="` + srv.URL + `/a.png"&gt;</pre>`
+	got := l.RewriteHTML(content, "../../")
+
+	if !strings.Contains(got, `<img src="../../remote-images/`) {
+		t.Errorf("Expected rewritten image under the path prefix, got %s", got)
+	}
+	if !strings.Contains(got, srv.URL+`/missing.png`) {
+		t.Error("Expected failed download to keep its remote URL")
+	}
+	if !strings.Contains(got, `&lt;img src="`+srv.URL+`/a.png"`) {
+		t.Error("Escaped markup inside code must not be rewritten")
+	}
+
+	// Rendered HTML escapes & in query strings; the lockfile keys the raw URL.
+	query := srv.URL + "/a.png?w=1&h=2"
+	if _, err := l.Localize(query); err != nil {
+		t.Fatal(err)
+	}
+	before := hits.Load()
+	escaped := l.RewriteHTML(`<img src="`+strings.ReplaceAll(query, "&", "&amp;")+`">`, "../")
+	if !strings.Contains(escaped, `<img src="../remote-images/`) || hits.Load() != before {
+		t.Errorf("Expected the escaped URL to hit the lockfile, got %s after %d fetches", escaped, hits.Load()-before)
+	}
+
+	distDir := t.TempDir()
+	if err := l.Emit(DiskOutput{}, distDir); err != nil {
+		t.Fatal(err)
+	}
+	emitted, _ := filepath.Glob(filepath.Join(distDir, "remote-images", "*.png"))
+	if len(emitted) != 1 {
+		t.Errorf("Expected 1 emitted image, got %v", emitted)
+	}
+}
+
+func TestLocalizePostImages(t *testing.T) {
+	srv, _ := newImageServer(t)
+	cfg := testRemoteImagesConfig(t)
+	blogDir := t.TempDir()
+
+	post := "---\ntitle: x\n---\n![a](" + srv.URL + "/a.png) and [the original](" + srv.URL + "/a.png)\n\n" +
+		"![b](<" + srv.URL + "/a.png> \"Title\")\n\n```md\n![code](" + srv.URL + "/attachment)\n![a](" + srv.URL + "/a.png)\n```\n"
+	postPath := filepath.Join(blogDir, "post.md")
+	if err := os.WriteFile(postPath, []byte(post), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := LocalizePostImages(blogDir, cfg, true)
+	if err != nil {
+		t.Fatalf("LocalizePostImages() error = %v", err)
+	}
+	if result.Images != 1 || result.Rewritten != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	got, _ := os.ReadFile(postPath)
+	for _, want := range []string{
+		"![a](/remote-images/",
+		"![b](</remote-images/",
+		"[the original](" + srv.URL + "/a.png)",
+		"![code](" + srv.URL + "/attachment)\n![a](" + srv.URL + "/a.png)\n```",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Expected markdown to contain %q, got %s", want, got)
+		}
+	}
+}
+
+func TestLocalizeRenderedImagesPublishesStoredImages(t *testing.T) {
+	srv, _ := newImageServer(t)
+	cfg := testRemoteImagesConfig(t)
+	l, err := NewImageLocalizer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := l.Localize(srv.URL + "/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Localizing is off, as after localize-images -rewrite, but the referenced copy
+	// must still be published.
+	posts := []Post{{Content: `<p><img src="../../remote-images/` + file + `" alt="a"></p>`}}
+	out := NewMemoryOutput()
+	if err := LocalizeRenderedImages(out, posts, cfg, "dist", "../../"); err != nil {
+		t.Fatalf("LocalizeRenderedImages() error = %v", err)
+	}
+	if _, err := fs.Stat(out.FS("dist"), "remote-images/"+file); err != nil {
+		t.Errorf("Expected the stored image to be published: %v", err)
+	}
+}
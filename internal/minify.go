@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+	"github.com/tdewolff/minify/v2/xml"
+)
+
+// minifyType describes one output type the minifier handles. Pattern matches every
+// media type the minifier is registered for, including those embedded in HTML such
+// as inline scripts and JSON-LD.
+type minifyType struct {
+	name       string
+	mediaType  string
+	pattern    *regexp.Regexp
+	extensions []string
+	fn         minify.MinifierFunc
+}
+
+// minifyTypes lists the supported types in report order.
+var minifyTypes = []minifyType{
+	{"html", "text/html", regexp.MustCompile(`^text/html$`), []string{".html", ".htm"}, html.Minify},
+	{"css", "text/css", regexp.MustCompile(`^text/css$`), []string{".css"}, css.Minify},
+	{"js", "application/javascript", regexp.MustCompile(`^(application|text)/(x-)?(java|ecma)script$`), []string{".js", ".mjs"}, js.Minify},
+	{"json", "application/json", regexp.MustCompile(`[/+]json$`), []string{".json"}, json.Minify},
+	{"svg", "image/svg+xml", regexp.MustCompile(`^image/svg\+xml$`), []string{".svg"}, svg.Minify},
+	{"xml", "text/xml", regexp.MustCompile(`^(text|application)/xml$`), []string{".xml"}, xml.Minify},
+}
+
+// MinifyStats accumulates the size savings for one output type.
+type MinifyStats struct {
+	Files    int
+	Original int64
+	Minified int64
+}
+
+// newMinifier registers every minifier type enabled in cfg.
+func newMinifier(cfg MinifyConfig) *minify.M {
+	m := minify.New()
+	for _, t := range minifyTypes {
+		if cfg.Enabled(t.name) {
+			m.AddFuncRegexp(t.pattern, t.fn)
+		}
+	}
+	return m
+}
+
+// minifyTypeFor returns the minifier type handling files with rel's extension.
+func minifyTypeFor(rel string) (minifyType, bool) {
+	ext := strings.ToLower(path.Ext(rel))
+	for _, t := range minifyTypes {
+		for _, e := range t.extensions {
+			if e == ext {
+				return t, true
+			}
+		}
+	}
+	return minifyType{}, false
+}
+
+// minifyBytes minifies data as the named type and records the savings. Disabled
+// types are returned unchanged.
+func (g *SiteGenerator) minifyBytes(name string, data []byte) ([]byte, error) {
+	if !g.Config.Minify.Enabled(name) {
+		return data, nil
+	}
+	var mediaType string
+	for _, t := range minifyTypes {
+		if t.name == name {
+			mediaType = t.mediaType
+		}
+	}
+	minified, err := g.minifier.Bytes(mediaType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, ok := g.minifyStats[name]
+	if !ok {
+		stats = &MinifyStats{}
+		g.minifyStats[name] = stats
+	}
+	stats.Files++
+	stats.Original += int64(len(data))
+	stats.Minified += int64(len(minified))
+	return minified, nil
+}
+
+// MinifyFiles minifies every non-HTML output in distDir in place. Pages are minified
+// as they are rendered, and files that were already minified or fingerprinted are
+// skipped so their content keeps matching their hashed names.
+func (g *SiteGenerator) MinifyFiles(distDir string) error {
+	fingerprinted := make(map[string]bool)
+	if g.Assets != nil {
+		for _, hashed := range g.Assets.Assets {
+			fingerprinted[hashed] = true
+		}
+	}
+
+	return g.Output.Walk(distDir, func(p string) error {
+		rel, err := filepath.Rel(distDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		t, ok := minifyTypeFor(rel)
+		if !ok || t.name == "html" || g.minified[rel] || fingerprinted[rel] {
+			return nil
+		}
+
+		data, err := g.Output.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		minified, err := g.minifyBytes(t.name, data)
+		if err != nil {
+			return fmt.Errorf("failed to minify %s: %w", rel, err)
+		}
+		if err := g.Output.WriteFile(p, minified); err != nil {
+			return fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		g.minified[rel] = true
+		return nil
+	})
+}
+
+// WriteMinifyReport prints the before and after sizes for every minified type,
+// followed by the overall savings.
+func (g *SiteGenerator) WriteMinifyReport(w io.Writer) {
+	var total MinifyStats
+	lines := make([]string, 0, len(minifyTypes))
+	for _, t := range minifyTypes {
+		stats, ok := g.minifyStats[t.name]
+		if !ok || stats.Original == 0 {
+			continue
+		}
+		total.Files += stats.Files
+		total.Original += stats.Original
+		total.Minified += stats.Minified
+		lines = append(lines, formatMinifyStats(strings.ToUpper(t.name), stats))
+	}
+	if total.Original == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Minification:")
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w, formatMinifyStats("Total", &total))
+}
+
+func formatMinifyStats(label string, s *MinifyStats) string {
+	savings := float64(s.Original-s.Minified) / float64(s.Original) * 100
+	return fmt.Sprintf("  %-5s %4d files  %10d -> %10d bytes (%.2f MB -> %.2f MB)  Savings: %.1f%%",
+		label, s.Files, s.Original, s.Minified, float64(s.Original)/1000000.0, float64(s.Minified)/1000000.0, savings)
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMinifyConfigEnabled(t *testing.T) {
+	cfg := MinifyConfig{Types: map[string]bool{"css": false, "json": true}}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"css", false},
+		{"json", true},
+		{"svg", true},
+	}
+	for _, tt := range tests {
+		if got := cfg.Enabled(tt.name); got != tt.want {
+			t.Errorf("Enabled(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinifyFiles(t *testing.T) {
+	distDir := t.TempDir()
+	files := map[string]string{
+		"styles.css":     "body {\n  color : red ;\n}\n",
+		"app.js":         "function add ( a , b ) {\n  return a + b ;\n}\n",
+		"api/data.json":  "{\n  \"a\" : 1\n}\n",
+		"icon.svg":       "<svg xmlns=\"http://www.w3.org/2000/svg\">\n  <!-- comment -->\n  <path d=\"M 0 0 L 10 10\" />\n</svg>\n",
+		"rss.xml":        "<?xml version=\"1.0\"?>\n<rss>\n  <item>\n    <title>x</title>\n  </item>\n</rss>\n",
+		"robots.txt":     "User-agent: *\n\n",
+		"page.html":      "<p>  untouched  </p>",
+		"skip/keep.json": "{\n  \"b\" : 2\n}\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(distDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &SiteConfig{Minify: MinifyConfig{Types: map[string]bool{"js": false}}}
+	g := New(cfg, "")
+	g.minified["skip/keep.json"] = true
+	if err := g.MinifyFiles(distDir); err != nil {
+		t.Fatalf("MinifyFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		changed bool
+	}{
+		{"styles.css", true},
+		{"app.js", false},
+		{"api/data.json", true},
+		{"icon.svg", true},
+		{"rss.xml", true},
+		{"robots.txt", false},
+		{"page.html", false},
+		{"skip/keep.json", false},
+	}
+	for _, tt := range tests {
+		got, err := os.ReadFile(filepath.Join(distDir, filepath.FromSlash(tt.name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed := string(got) != files[tt.name]; changed != tt.changed {
+			t.Errorf("%s changed = %v, want %v (got %q)", tt.name, changed, tt.changed, got)
+		}
+	}
+
+	if _, ok := g.minifyStats["js"]; ok {
+		t.Error("Disabled types must not be reported")
+	}
+	var report bytes.Buffer
+	g.WriteMinifyReport(&report)
+	for _, want := range []string{"CSS", "JSON", "SVG", "XML", "Total"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("Expected report to mention %s, got:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestMinifyFilesSkipsWrittenAssets(t *testing.T) {
+	distDir := t.TempDir()
+	svg := "<svg xmlns=\"http://www.w3.org/2000/svg\">\n  <!-- comment -->\n</svg>\n"
+	if err := os.WriteFile(filepath.Join(distDir, "icon.svg"), []byte(svg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(&SiteConfig{Minify: MinifyConfig{Types: map[string]bool{"svg": false}}}, "")
+	if _, err := g.writeAsset(distDir, "search-index.json", []byte("[\n  {\"a\" : 1}\n]\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.MinifyFiles(distDir); err != nil {
+		t.Fatalf("MinifyFiles() error = %v", err)
+	}
+
+	if stats := g.minifyStats["json"]; stats == nil || stats.Files != 1 {
+		t.Errorf("Expected the written JSON asset to be minified once, got %+v", stats)
+	}
+	if got, err := os.ReadFile(filepath.Join(distDir, "icon.svg")); err != nil || string(got) != svg {
+		t.Errorf("Expected icon.svg to stay untouched, got %q, %v", got, err)
+	}
+	if got, err := g.minifier.String("image/svg+xml", svg); err == nil {
+		t.Errorf("Expected no minifier for SVG when svg is disabled, got %q", got)
+	}
+}
+
+func TestRenderPageMinifiesInlineScripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := `{{ define "base.html" }}<html><body>{{ template "content" . }}</body></html>{{ end }}`
+	page := `{{ define "content" }}<script>
+    const answer = 40 + 2;
+    console.log( answer );
+</script>{{ end }}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "base.html"), []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "page.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(&SiteConfig{}, tmpDir)
+	distDir := filepath.Join(tmpDir, "dist")
+	if err := g.RenderPage(distDir, "page.html", "page.html", "", PageData{}); err != nil {
+		t.Fatalf("RenderPage() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(distDir, "page.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "\n    const") {
+		t.Errorf("Expected inline script to be minified, got %s", got)
+	}
+}
@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // background images may be JPEG
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	ogWidth   = 1200
+	ogHeight  = 630
+	ogPadding = 80
+
+	// ogDateLayout formats the entry date drawn on a card.
+	ogDateLayout = "January 02, 2006"
+)
+
+// ogImageName returns the site-relative path of the Open Graph card of the entry at
+// pagePath, next to the page itself.
+func ogImageName(pagePath string) string {
+	return strings.TrimSuffix(pagePath, ".html") + ".og.png"
+}
+
+// GenerateOGImages renders a PNG preview card for every collection entry, such as
+// blog/<slug>.og.png for a post. When a cache directory is configured, cards are reused
+// until anything drawn on them changes; entries outside the blog are cached under their
+// collection name so equal slugs do not evict each other.
+func (g *SiteGenerator) GenerateOGImages(distDir string, data *ContentData) error {
+	cfg := g.Config.OGImage
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var background string
+	if cfg.CacheDir != "" {
+		if err := os.MkdirAll(cfg.CacheDir, 0755); err != nil {
+			return fmt.Errorf("failed to create og cache dir %s: %w", cfg.CacheDir, err)
+		}
+		if cfg.BackgroundImage != "" {
+			data, err := os.ReadFile(cfg.BackgroundImage)
+			if err != nil {
+				return fmt.Errorf("failed to open og background image: %w", err)
+			}
+			sum := sha256.Sum256(data)
+			background = hex.EncodeToString(sum[:])
+		}
+	}
+
+	var renderer *ogRenderer
+	for _, e := range sortedEntries(g.collections(data), func(Collection) bool { return true }) {
+		post := e.Post
+		dst := filepath.Join(distDir, filepath.FromSlash(ogImageName(e.Path)))
+		name := post.Slug
+		if e.Collection != DefaultCollection().Name {
+			name = e.Collection + "-" + post.Slug
+		}
+
+		var cached string
+		if cfg.CacheDir != "" {
+			cached = filepath.Join(cfg.CacheDir, name+"-"+g.ogCacheKey(post, background)+".png")
+			if card, err := os.ReadFile(cached); err == nil {
+				if err := g.Output.WriteFile(dst, card); err != nil {
+					return fmt.Errorf("failed to copy cached og image for %s: %w", post.Slug, err)
+				}
+				continue
+			}
+		}
+
+		if renderer == nil {
+			r, err := newOGRenderer(cfg)
+			if err != nil {
+				return err
+			}
+			renderer = r
+		}
+
+		card, err := renderer.Render(post, g.Config.Landing.Title)
+		if err != nil {
+			return fmt.Errorf("failed to render og image for %s: %w", post.Slug, err)
+		}
+		if err := g.Output.WriteFile(dst, card); err != nil {
+			return fmt.Errorf("failed to write og image for %s: %w", post.Slug, err)
+		}
+
+		if cached != "" {
+			// The glob also matches the cards of slugs that extend name, such as
+			// foo-bar for foo, so only names followed by a bare cache key are stale.
+			own := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `-[0-9a-f]{12}\.png$`)
+			stale, _ := filepath.Glob(filepath.Join(cfg.CacheDir, name+"-*.png"))
+			for _, old := range stale {
+				if own.MatchString(filepath.Base(old)) {
+					os.Remove(old)
+				}
+			}
+			if err := os.WriteFile(cached, card, 0644); err != nil {
+				return fmt.Errorf("failed to cache og image for %s: %w", post.Slug, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ogCacheKey identifies a rendered card by the title, date and tags drawn on it and the
+// card styling, including the content hash of the background image, so editing the
+// body or description of a post does not force a re-render.
+func (g *SiteGenerator) ogCacheKey(post Post, background string) string {
+	cfg := g.Config.OGImage
+	date := ""
+	if !post.Date.IsZero() {
+		date = post.Date.Format(ogDateLayout)
+	}
+	h := sha256.New()
+	for _, part := range []string{post.Title, date, strings.Join(post.Tags, "\x00"), g.Config.Landing.Title, cfg.Background, cfg.BackgroundImage, background, cfg.Foreground, cfg.Accent} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// ogRenderer holds the parsed fonts and colours shared by every card in a build.
+type ogRenderer struct {
+	background image.Image
+	foreground color.Color
+	accent     color.Color
+	bold       *opentype.Font
+	regular    *opentype.Font
+}
+
+func newOGRenderer(cfg OGImageConfig) (*ogRenderer, error) {
+	bg, err := parseHexColor(cfg.Background, color.RGBA{0x02, 0x06, 0x17, 0xff})
+	if err != nil {
+		return nil, fmt.Errorf("invalid og background: %w", err)
+	}
+	fg, err := parseHexColor(cfg.Foreground, color.RGBA{0xe2, 0xe8, 0xf0, 0xff})
+	if err != nil {
+		return nil, fmt.Errorf("invalid og foreground: %w", err)
+	}
+	accent, err := parseHexColor(cfg.Accent, color.RGBA{0xa7, 0x8b, 0xfa, 0xff})
+	if err != nil {
+		return nil, fmt.Errorf("invalid og accent: %w", err)
+	}
+
+	r := &ogRenderer{background: image.NewUniform(bg), foreground: fg, accent: accent}
+
+	if cfg.BackgroundImage != "" {
+		f, err := os.Open(cfg.BackgroundImage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open og background image: %w", err)
+		}
+		defer f.Close()
+		src, _, err := image.Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode og background image: %w", err)
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, ogWidth, ogHeight))
+		xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), src, src.Bounds(), draw.Src, nil)
+		r.background = scaled
+	}
+
+	if r.bold, err = opentype.Parse(gobold.TTF); err != nil {
+		return nil, fmt.Errorf("failed to parse bold font: %w", err)
+	}
+	if r.regular, err = opentype.Parse(goregular.TTF); err != nil {
+		return nil, fmt.Errorf("failed to parse regular font: %w", err)
+	}
+	return r, nil
+}
+
+// Render draws the card for post and returns it PNG-encoded.
+func (r *ogRenderer) Render(post Post, siteName string) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, ogWidth, ogHeight))
+	draw.Draw(img, img.Bounds(), r.background, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 16, ogHeight), image.NewUniform(r.accent), image.Point{}, draw.Src)
+
+	small, err := r.face(r.regular, 30)
+	if err != nil {
+		return nil, err
+	}
+	defer small.Close()
+
+	// Date
+	if !post.Date.IsZero() {
+		r.drawText(img, small, r.accent, ogPadding, ogPadding+30, post.Date.Format(ogDateLayout))
+	}
+
+	// Title: shrink the font until the wrapped title fits above the tag line.
+	const titleTop, titleBottom = ogPadding + 70, ogHeight - ogPadding - 110
+	maxWidth := ogWidth - 2*ogPadding
+	var lines []string
+	var title font.Face
+	size := 72.0
+	for {
+		if title, err = r.face(r.bold, size); err != nil {
+			return nil, err
+		}
+		lines = wrapText(title, post.Title, maxWidth)
+		if titleTop+len(lines)*int(size*1.2) <= titleBottom || size <= 40 {
+			break
+		}
+		title.Close()
+		size -= 8
+	}
+	defer title.Close()
+
+	lineHeight := int(size * 1.2)
+	if maxLines := (titleBottom - titleTop) / lineHeight; len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += "…"
+	}
+	y := titleTop
+	for _, line := range lines {
+		y += lineHeight
+		r.drawText(img, title, r.foreground, ogPadding, y, line)
+	}
+
+	// Tags and site name along the bottom edge
+	if len(post.Tags) > 0 {
+		tags := "#" + strings.Join(post.Tags, "  #")
+		r.drawText(img, small, r.accent, ogPadding, ogHeight-ogPadding-50, tags)
+	}
+	r.drawText(img, small, r.foreground, ogPadding, ogHeight-ogPadding, siteName)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *ogRenderer) face(f *opentype.Font, size float64) (font.Face, error) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font face: %w", err)
+	}
+	return face, nil
+}
+
+func (r *ogRenderer) drawText(img draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// wrapText greedily breaks text into lines no wider than maxWidth pixels.
+func wrapText(face font.Face, text string, maxWidth int) []string {
+	var lines []string
+	var current string
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current != "" && font.MeasureString(face, candidate).Ceil() > maxWidth {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current = candidate
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// parseHexColor parses "#rrggbb", returning fallback for an empty string.
+func parseHexColor(s string, fallback color.RGBA) (color.RGBA, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	hexStr := strings.TrimPrefix(s, "#")
+	if len(hexStr) != 6 {
+		return color.RGBA{}, fmt.Errorf("expected #rrggbb, got %q", s)
+	}
+	v, err := strconv.ParseUint(hexStr, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("expected #rrggbb, got %q", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateOGImages(t *testing.T) {
+	posts := []Post{
+		{
+			Frontmatter: Frontmatter{
+				Title: "A Fairly Long Post Title That Should Wrap Across Multiple Lines On The Card",
+				Date:  time.Date(2026, 6, 11, 0, 0, 0, 0, time.UTC),
+				Tags:  []string{"go", "testing"},
+			},
+			Slug: "card-post",
+		},
+	}
+	data := &ContentData{Posts: posts}
+
+	t.Run("Disabled Writes Nothing", func(t *testing.T) {
+		distDir := t.TempDir()
+		gen := New(createConfig(), "")
+		if err := gen.GenerateOGImages(distDir, data); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(distDir, "blog", "card-post.og.png")); err == nil {
+			t.Error("Expected no card when og images are disabled")
+		}
+	})
+
+	t.Run("Renders PNG Card", func(t *testing.T) {
+		distDir := t.TempDir()
+		cfg := createConfig()
+		cfg.OGImage = OGImageConfig{Enabled: true}
+		gen := New(cfg, "")
+
+		if err := gen.GenerateOGImages(distDir, data); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(distDir, "blog", "card-post.og.png"))
+		if err != nil {
+			t.Fatalf("Card not written: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("Card is not a valid PNG: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != ogWidth || b.Dy() != ogHeight {
+			t.Errorf("Expected %dx%d card, got %dx%d", ogWidth, ogHeight, b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("Cache Reused Until Title Changes", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		cfg := createConfig()
+		cfg.OGImage = OGImageConfig{Enabled: true, CacheDir: cacheDir}
+		gen := New(cfg, "")
+
+		if err := gen.GenerateOGImages(t.TempDir(), data); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		cached, _ := filepath.Glob(filepath.Join(cacheDir, "card-post-*.png"))
+		if len(cached) != 1 {
+			t.Fatalf("Expected 1 cached card, got %v", cached)
+		}
+
+		// Overwrite the cached card: an unchanged title must reuse it verbatim.
+		if err := os.WriteFile(cached[0], []byte("cached"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		distDir := t.TempDir()
+		if err := gen.GenerateOGImages(distDir, data); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		got, _ := os.ReadFile(filepath.Join(distDir, "blog", "card-post.og.png"))
+		if string(got) != "cached" {
+			t.Error("Expected cached card to be reused")
+		}
+
+		// A new title renders a fresh card and evicts the stale one.
+		renamed := &ContentData{Posts: []Post{{Frontmatter: Frontmatter{Title: "Renamed"}, Slug: "card-post"}}}
+		if err := gen.GenerateOGImages(distDir, renamed); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		got, _ = os.ReadFile(filepath.Join(distDir, "blog", "card-post.og.png"))
+		if string(got) == "cached" {
+			t.Error("Expected card to be re-rendered after title change")
+		}
+		if cached, _ := filepath.Glob(filepath.Join(cacheDir, "card-post-*.png")); len(cached) != 1 {
+			t.Errorf("Expected stale cache entry to be removed, got %v", cached)
+		}
+	})
+
+	t.Run("Cache Keeps Longer Slugs", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		cfg := createConfig()
+		cfg.OGImage = OGImageConfig{Enabled: true, CacheDir: cacheDir}
+		gen := New(cfg, "")
+
+		both := &ContentData{Posts: []Post{
+			{Frontmatter: Frontmatter{Title: "Foo"}, Slug: "foo"},
+			{Frontmatter: Frontmatter{Title: "Foo Bar"}, Slug: "foo-bar"},
+		}}
+		if err := gen.GenerateOGImages(t.TempDir(), both); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		before, _ := filepath.Glob(filepath.Join(cacheDir, "foo-bar-*.png"))
+		if len(before) != 1 {
+			t.Fatalf("Expected a cached card for foo-bar, got %v", before)
+		}
+
+		both.Posts[0].Title = "Foo Renamed"
+		if err := gen.GenerateOGImages(t.TempDir(), both); err != nil {
+			t.Fatalf("GenerateOGImages() error = %v", err)
+		}
+		if after, _ := filepath.Glob(filepath.Join(cacheDir, "foo-bar-*.png")); len(after) != 1 || after[0] != before[0] {
+			t.Errorf("Expected re-rendering foo to keep the foo-bar card, got %v", after)
+		}
+	})
+
+	t.Run("Cache Follows Card Contents", func(t *testing.T) {
+		dir := t.TempDir()
+		background := filepath.Join(dir, "bg.png")
+		writeBackground := func(c color.Color) {
+			t.Helper()
+			img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+			draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(background, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeBackground(color.Black)
+
+		cfg := createConfig()
+		cfg.OGImage = OGImageConfig{Enabled: true, CacheDir: filepath.Join(dir, "cache"), BackgroundImage: background}
+		gen := New(cfg, "")
+		post := Post{Frontmatter: Frontmatter{Title: "Same Title", Date: time.Date(2026, 6, 11, 0, 0, 0, 0, time.UTC)}, Slug: "card-post"}
+		key := func() string {
+			t.Helper()
+			if err := gen.GenerateOGImages(t.TempDir(), &ContentData{Posts: []Post{post}}); err != nil {
+				t.Fatalf("GenerateOGImages() error = %v", err)
+			}
+			cached, _ := filepath.Glob(filepath.Join(dir, "cache", "card-post-*.png"))
+			if len(cached) != 1 {
+				t.Fatalf("Expected 1 cached card, got %v", cached)
+			}
+			return cached[0]
+		}
+
+		first := key()
+		post.Date = post.Date.AddDate(0, 0, 1)
+		dated := key()
+		post.Tags = []string{"go"}
+		tagged := key()
+		writeBackground(color.White)
+		restyled := key()
+		if first == dated || dated == tagged || tagged == restyled {
+			t.Errorf("Expected the date, tags and background image to change the cache key, got %s, %s, %s, %s", first, dated, tagged, restyled)
+		}
+	})
+
+	t.Run("Invalid Colour", func(t *testing.T) {
+		cfg := createConfig()
+		cfg.OGImage = OGImageConfig{Enabled: true, Background: "blue"}
+		gen := New(cfg, "")
+		if err := gen.GenerateOGImages(t.TempDir(), data); err == nil {
+			t.Error("Expected error for invalid background colour")
+		}
+	})
+}
@@ -0,0 +1,433 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output is where a build writes its files. Names are OS paths, as passed to the
+// os package, so generators can keep joining them onto the build directory.
+type Output interface {
+	// WriteFile writes data to name, creating any missing parent directories.
+	WriteFile(name string, data []byte) error
+	// ReadFile returns the contents of a file written earlier.
+	ReadFile(name string) ([]byte, error)
+	// Rename moves a file to a new name.
+	Rename(oldname, newname string) error
+	// Walk calls fn for every regular file under root in lexical order. A root that
+	// does not exist holds no files.
+	Walk(root string, fn func(name string) error) error
+	// RemoveAll removes name and everything below it.
+	RemoveAll(name string) error
+	// Stage returns an empty build directory that Publish later moves to distDir.
+	Stage(distDir string) (string, error)
+	// Publish replaces distDir with buildDir, keeping the old distDir at PrevDir.
+	Publish(buildDir, distDir string) error
+	// FS returns the tree under dir for reading and serving.
+	FS(dir string) fs.FS
+}
+
+// CopyToOutput copies every regular file in src into the dst directory of out.
+func CopyToOutput(out Output, src fs.FS, dst string) error {
+	return fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		data, err := fs.ReadFile(src, p)
+		if err != nil {
+			return err
+		}
+		return out.WriteFile(filepath.Join(dst, filepath.FromSlash(p)), data)
+	})
+}
+
+// ============================================================================
+// Disk Output
+// ============================================================================
+
+// DiskOutput writes builds to the filesystem, swapping finished builds into place
+// with renames.
+type DiskOutput struct{}
+
+func (DiskOutput) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+func (DiskOutput) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (DiskOutput) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+func (DiskOutput) Walk(root string, fn func(name string) error) error {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		return fn(p)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, statErr := os.Stat(root); errors.Is(statErr, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	return err
+}
+
+func (DiskOutput) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+func (DiskOutput) Stage(distDir string) (string, error) {
+	return newBuildDir(distDir)
+}
+
+func (DiskOutput) Publish(buildDir, distDir string) error {
+	return swapBuildDir(buildDir, distDir)
+}
+
+func (DiskOutput) FS(dir string) fs.FS {
+	return os.DirFS(dir)
+}
+
+// ============================================================================
+// Memory Output
+// ============================================================================
+
+// MemoryOutput keeps a build in memory, so tests can inspect outputs without temp
+// directories and the dev server can serve a build without writing it to disk. It
+// is safe for concurrent use.
+type MemoryOutput struct {
+	mu     sync.RWMutex
+	files  map[string]memoryFile
+	staged int
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryOutput returns an empty in-memory output.
+func NewMemoryOutput() *MemoryOutput {
+	return &MemoryOutput{files: make(map[string]memoryFile)}
+}
+
+// memoryKey normalizes an OS path into the slash-separated form files are stored under.
+func memoryKey(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// memoryPrefix returns the key prefix shared by everything below dir.
+func memoryPrefix(dir string) string {
+	key := memoryKey(dir)
+	switch {
+	case key == ".":
+		return ""
+	case strings.HasSuffix(key, "/"):
+		return key
+	}
+	return key + "/"
+}
+
+func (m *MemoryOutput) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[memoryKey(name)] = memoryFile{data: bytes.Clone(data), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryOutput) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[memoryKey(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+func (m *MemoryOutput) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memoryKey(oldname)
+	f, ok := m.files[key]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	delete(m.files, key)
+	m.files[memoryKey(newname)] = f
+	return nil
+}
+
+// Walk visits the files under root as they were when it was called, so fn may write
+// to the output.
+func (m *MemoryOutput) Walk(root string, fn func(name string) error) error {
+	for _, key := range m.keys(root) {
+		if err := fn(filepath.FromSlash(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keys returns the sorted keys of the files under root, or root itself if it is a file.
+func (m *MemoryOutput) keys(root string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	if _, ok := m.files[memoryKey(root)]; ok {
+		keys = append(keys, memoryKey(root))
+	}
+	prefix := memoryPrefix(root)
+	for key := range m.files {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *MemoryOutput) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeAll(name)
+	return nil
+}
+
+func (m *MemoryOutput) removeAll(name string) {
+	delete(m.files, memoryKey(name))
+	prefix := memoryPrefix(name)
+	for key := range m.files {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.files, key)
+		}
+	}
+}
+
+func (m *MemoryOutput) Stage(distDir string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.staged++
+	dir := fmt.Sprintf("%s.build-%d", filepath.Clean(distDir), m.staged)
+	m.removeAll(dir)
+	return dir, nil
+}
+
+func (m *MemoryOutput) Publish(buildDir, distDir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := PrevDir(distDir)
+	m.removeAll(prev)
+	m.move(distDir, prev)
+	m.move(buildDir, distDir)
+	return nil
+}
+
+// move renames every file below from to the same place below to.
+func (m *MemoryOutput) move(from, to string) {
+	fromPrefix, toPrefix := memoryPrefix(from), memoryPrefix(to)
+	for key, f := range m.files {
+		if strings.HasPrefix(key, fromPrefix) {
+			delete(m.files, key)
+			m.files[toPrefix+strings.TrimPrefix(key, fromPrefix)] = f
+		}
+	}
+}
+
+// FS returns a live view of the files under dir.
+func (m *MemoryOutput) FS(dir string) fs.FS {
+	return &memoryFS{out: m, prefix: memoryPrefix(dir)}
+}
+
+// memoryFS implements fs.FS over the files of a MemoryOutput below prefix.
+type memoryFS struct {
+	out    *MemoryOutput
+	prefix string
+}
+
+func (f *memoryFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.out.mu.RLock()
+	defer f.out.mu.RUnlock()
+
+	if file, ok := f.out.files[f.prefix+name]; ok {
+		info := memoryFileInfo{name: path.Base(name), size: int64(len(file.data)), modTime: file.modTime}
+		return &memoryOpenFile{Reader: bytes.NewReader(file.data), info: info}, nil
+	}
+
+	dirPrefix := f.prefix
+	if name != "." {
+		dirPrefix += name + "/"
+	}
+	children := make(map[string]fs.DirEntry)
+	for key, file := range f.out.files {
+		rest, ok := strings.CutPrefix(key, dirPrefix)
+		if !ok {
+			continue
+		}
+		child, _, isDir := strings.Cut(rest, "/")
+		if _, seen := children[child]; seen {
+			continue
+		}
+		info := memoryFileInfo{name: child, dir: isDir}
+		if !isDir {
+			info.size, info.modTime = int64(len(file.data)), file.modTime
+		}
+		children[child] = fs.FileInfoToDirEntry(info)
+	}
+	if len(children) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, e := range children {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return &listedDir{info: memoryFileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) ModTime() time.Time { return i.modTime }
+func (i memoryFileInfo) IsDir() bool        { return i.dir }
+func (i memoryFileInfo) Sys() any           { return nil }
+
+func (i memoryFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// memoryOpenFile is an open file of a memoryFS. It is seekable, so it can be passed
+// to http.ServeContent.
+type memoryOpenFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+func (f *memoryOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memoryOpenFile) Close() error               { return nil }
+
+// listedDir is an open directory whose entries were listed when it was opened.
+type listedDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *listedDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *listedDir) Close() error               { return nil }
+
+func (d *listedDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *listedDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// ============================================================================
+// Write-Through Output
+// ============================================================================
+
+// WriteThroughOutput writes every file both to Disk and to Memory, and reads from
+// Memory, so a build can be served from memory while dist stays up to date.
+type WriteThroughOutput struct {
+	Memory *MemoryOutput
+	Disk   Output
+}
+
+// NewWriteThroughOutput mirrors the writes to disk in a fresh memory output.
+func NewWriteThroughOutput(disk Output) *WriteThroughOutput {
+	return &WriteThroughOutput{Memory: NewMemoryOutput(), Disk: disk}
+}
+
+func (w *WriteThroughOutput) WriteFile(name string, data []byte) error {
+	if err := w.Disk.WriteFile(name, data); err != nil {
+		return err
+	}
+	return w.Memory.WriteFile(name, data)
+}
+
+func (w *WriteThroughOutput) ReadFile(name string) ([]byte, error) {
+	return w.Memory.ReadFile(name)
+}
+
+func (w *WriteThroughOutput) Rename(oldname, newname string) error {
+	if err := w.Disk.Rename(oldname, newname); err != nil {
+		return err
+	}
+	return w.Memory.Rename(oldname, newname)
+}
+
+func (w *WriteThroughOutput) Walk(root string, fn func(name string) error) error {
+	return w.Memory.Walk(root, fn)
+}
+
+func (w *WriteThroughOutput) RemoveAll(name string) error {
+	if err := w.Disk.RemoveAll(name); err != nil {
+		return err
+	}
+	return w.Memory.RemoveAll(name)
+}
+
+func (w *WriteThroughOutput) Stage(distDir string) (string, error) {
+	dir, err := w.Disk.Stage(distDir)
+	if err != nil {
+		return "", err
+	}
+	w.Memory.RemoveAll(dir)
+	return dir, nil
+}
+
+func (w *WriteThroughOutput) Publish(buildDir, distDir string) error {
+	if err := w.Disk.Publish(buildDir, distDir); err != nil {
+		return err
+	}
+	return w.Memory.Publish(buildDir, distDir)
+}
+
+func (w *WriteThroughOutput) FS(dir string) fs.FS {
+	return w.Memory.FS(dir)
+}
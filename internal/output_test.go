@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// readOutputTree returns every file under dir in out, keyed by slash-separated
+// relative path.
+func readOutputTree(t *testing.T, out Output, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := out.Walk(dir, func(name string) error {
+		data, err := out.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk(%s) error = %v", dir, err)
+	}
+	return files
+}
+
+func TestOutputs(t *testing.T) {
+	outputs := map[string]func(t *testing.T) Output{
+		"Disk":          func(t *testing.T) Output { return DiskOutput{} },
+		"Memory":        func(t *testing.T) Output { return NewMemoryOutput() },
+		"Write-Through": func(t *testing.T) Output { return NewWriteThroughOutput(DiskOutput{}) },
+	}
+
+	for name, newOutput := range outputs {
+		t.Run(name, func(t *testing.T) {
+			out := newOutput(t)
+			distDir := filepath.Join(t.TempDir(), "dist")
+
+			build := func(files map[string]string) {
+				t.Helper()
+				buildDir, err := out.Stage(distDir)
+				if err != nil {
+					t.Fatalf("Stage() error = %v", err)
+				}
+				for rel, content := range files {
+					if err := out.WriteFile(filepath.Join(buildDir, filepath.FromSlash(rel)), []byte(content)); err != nil {
+						t.Fatalf("WriteFile(%s) error = %v", rel, err)
+					}
+				}
+				if err := out.Publish(buildDir, distDir); err != nil {
+					t.Fatalf("Publish() error = %v", err)
+				}
+			}
+
+			build(map[string]string{"index.html": "first", "blog/post.html": "post"})
+			build(map[string]string{"index.html": "second", "styles.css": "body{}"})
+
+			want := map[string]string{"index.html": "second", "styles.css": "body{}"}
+			if got := readOutputTree(t, out, distDir); !reflect.DeepEqual(got, want) {
+				t.Errorf("dist = %v, want %v", got, want)
+			}
+			wantPrev := map[string]string{"index.html": "first", "blog/post.html": "post"}
+			if got := readOutputTree(t, out, PrevDir(distDir)); !reflect.DeepEqual(got, wantPrev) {
+				t.Errorf("dist.prev = %v, want %v", got, wantPrev)
+			}
+
+			css := filepath.Join(distDir, "styles.css")
+			hashed := filepath.Join(distDir, "styles.0123abcd.css")
+			if err := out.Rename(css, hashed); err != nil {
+				t.Fatalf("Rename() error = %v", err)
+			}
+			if _, err := out.ReadFile(css); err == nil {
+				t.Error("Expected the old name to be gone after Rename")
+			}
+
+			data, err := fs.ReadFile(out.FS(distDir), "styles.0123abcd.css")
+			if err != nil || string(data) != "body{}" {
+				t.Errorf("FS ReadFile = %q, %v", data, err)
+			}
+			if err := fstest.TestFS(out.FS(distDir), "index.html", "styles.0123abcd.css"); err != nil {
+				t.Error(err)
+			}
+
+			if err := out.RemoveAll(distDir); err != nil {
+				t.Fatalf("RemoveAll() error = %v", err)
+			}
+			if got := readOutputTree(t, out, distDir); len(got) != 0 {
+				t.Errorf("Expected no files after RemoveAll, got %v", got)
+			}
+		})
+	}
+}
+
+func TestWriteThroughOutput(t *testing.T) {
+	out := NewWriteThroughOutput(DiskOutput{})
+	name := filepath.Join(t.TempDir(), "dist", "blog", "post.html")
+	if err := out.WriteFile(name, []byte("<p>post</p>")); err != nil {
+		t.Fatal(err)
+	}
+
+	onDisk, err := os.ReadFile(name)
+	if err != nil || string(onDisk) != "<p>post</p>" {
+		t.Errorf("disk = %q, %v", onDisk, err)
+	}
+	inMemory, err := out.Memory.ReadFile(name)
+	if err != nil || string(inMemory) != "<p>post</p>" {
+		t.Errorf("memory = %q, %v", inMemory, err)
+	}
+}
+
+func TestMemoryOutputCopiesData(t *testing.T) {
+	out := NewMemoryOutput()
+	data := []byte("original")
+	if err := out.WriteFile("dist/a.txt", data); err != nil {
+		t.Fatal(err)
+	}
+	copy(data, "modified")
+
+	got, err := out.ReadFile("dist/a.txt")
+	if err != nil || string(got) != "original" {
+		t.Errorf("ReadFile() = %q, %v; want the bytes as written", got, err)
+	}
+	if _, err := out.ReadFile("dist/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a missing file, got %v", err)
+	}
+	if _, err := out.FS("dist").Open("../a.txt"); err == nil {
+		t.Error("Expected FS to reject paths outside its root")
+	}
+}
+
+func TestRunPipelineInMemory(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	out := NewMemoryOutput()
+
+	count, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithOutput(out))
+	if err != nil {
+		t.Fatalf("RunPipeline failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 post to be built, got %d", count)
+	}
+
+	if _, err := os.Stat(distDir); !os.IsNotExist(err) {
+		t.Errorf("Expected an in-memory build to leave %s untouched, got %v", distDir, err)
+	}
+	leftovers, _ := filepath.Glob(distDir + ".build-*")
+	if len(leftovers) != 0 {
+		t.Errorf("Expected no build dirs on disk, got %v", leftovers)
+	}
+
+	site := out.FS(distDir)
+	post, err := fs.ReadFile(site, "blog/test.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(post), "Integration Post") {
+		t.Errorf("Expected rendered post in memory, got %s", post)
+	}
+	if asset, err := fs.ReadFile(site, "test.txt"); err != nil || string(asset) != "assets" {
+		t.Errorf("Expected public assets to be copied into memory, got %q, %v", asset, err)
+	}
+	for _, name := range []string{"index.html", "sitemap.xml", "rss.xml", "api/manifest.json"} {
+		if _, err := fs.Stat(site, name); err != nil {
+			t.Errorf("Expected %s in the in-memory build: %v", name, err)
+		}
+	}
+	for rel := range readOutputTree(t, out, filepath.Dir(distDir)) {
+		if strings.Contains(rel, ".build-") {
+			t.Errorf("Expected the staged build to be removed from memory, found %s", rel)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPageTemplate renders standalone pages that do not name a template.
+const defaultPageTemplate = "page.html"
+
+// GetPages parses every Markdown file below pagesDir into a page at the same relative
+// path, skipping drafts. A missing pagesDir holds no pages.
+func GetPages(pagesDir string, opts ...ParseOption) ([]Page, error) {
+	var pages []Page
+	err := filepath.WalkDir(pagesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == pagesDir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		rel, err := filepath.Rel(pagesDir, path)
+		if err != nil {
+			return err
+		}
+		page, err := ParsePage(path, strings.TrimSuffix(filepath.ToSlash(rel), ".md")+".html", opts...)
+		if err != nil {
+			return fmt.Errorf("failed to parse page %s: %w", rel, err)
+		}
+		if page != nil && !page.Draft {
+			pages = append(pages, *page)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Path < pages[j].Path })
+	return pages, nil
+}
+
+// ParsePage reads a Markdown page that will be served at pagePath, decodes its
+// frontmatter, and converts the body to HTML. It returns nil for files without
+// frontmatter.
+func ParsePage(path, pagePath string, opts ...ParseOption) (*Page, error) {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var fm PageFrontmatter
+	body, err := readMarkdownSource(path, &fm)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	if fm.Title == "" {
+		return nil, errors.New("missing title")
+	}
+	for _, menu := range fm.Nav {
+		if menu != "header" && menu != "footer" {
+			return nil, fmt.Errorf("unknown nav menu %q, expected header or footer", menu)
+		}
+	}
+
+	var buf bytes.Buffer
+	md := newMarkdown(cfg, filepath.Dir(path), pagePathPrefix(pagePath))
+	if err := md.Convert(body, &buf); err != nil {
+		return nil, err
+	}
+
+	return &Page{PageFrontmatter: fm, Path: pagePath, Content: buf.String()}, nil
+}
+
+// pagePathPrefix returns the relative path from a page back to the site root.
+func pagePathPrefix(pagePath string) string {
+	return strings.Repeat("../", strings.Count(pagePath, "/"))
+}
+
+// AddPageNavigation appends the pages that ask for a navigation menu to it, in page
+// order, unless the menu already links to them.
+func AddPageNavigation(nav *NavigationConfig, pages []Page) {
+	for _, p := range pages {
+		for _, menu := range p.Nav {
+			items := &nav.Header
+			if menu == "footer" {
+				items = &nav.Footer
+			}
+			if !hasNavItem(*items, p.Path) {
+				*items = append(*items, NavItem{Href: p.Path, Text: p.Title})
+			}
+		}
+	}
+}
+
+func hasNavItem(items []NavItem, href string) bool {
+	for _, item := range items {
+		if strings.TrimPrefix(item.Href, "/") == href {
+			return true
+		}
+	}
+	return false
+}
+
+// GeneratePages renders the standalone Markdown pages. A page may not replace one
+// the generator rendered before it.
+func (g *SiteGenerator) GeneratePages(distDir string, data *ContentData) error {
+	for i := range data.Pages {
+		page := &data.Pages[i]
+		if _, ok := g.pages[page.Path]; ok {
+			return fmt.Errorf("page %s conflicts with a generated page", page.Path)
+		}
+		tmplPath := page.Template
+		if tmplPath == "" {
+			tmplPath = defaultPageTemplate
+		}
+		if err := g.RenderPage(distDir, filepath.FromSlash(page.Path), tmplPath, page.Title, PageData{
+			Page:       page,
+			Path:       page.Path,
+			Layout:     page.Layout,
+			PathPrefix: pagePathPrefix(page.Path),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantPaths []string
+		wantErr   string
+	}{
+		{
+			name: "Nested Pages",
+			files: map[string]string{
+				"uses.md":      "---\ntitle: Uses\nnav: [header]\n---\nMy **tools**.",
+				"notes/now.md": "---\ntitle: Now\nlayout: plain\n---\n![Desk](desk.png)",
+				"draft.md":     "---\ntitle: Draft\ndraft: true\n---\nSoon.",
+				"plain.md":     "No frontmatter.",
+				"notes.txt":    "Not markdown.",
+			},
+			wantPaths: []string{"notes/now.html", "uses.html"},
+		},
+		{
+			name:    "Missing Title",
+			files:   map[string]string{"uses.md": "---\ndescription: Tools\n---\nBody"},
+			wantErr: "missing title",
+		},
+		{
+			name:    "Unknown Nav Menu",
+			files:   map[string]string{"uses.md": "---\ntitle: Uses\nnav: [sidebar]\n---\nBody"},
+			wantErr: `unknown nav menu "sidebar"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := GetPages(writeTree(t, tt.files))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetPages() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, p := range pages {
+				paths = append(paths, p.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("GetPages() paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+
+	pages, err := GetPages(writeTree(t, map[string]string{
+		"notes/now.md": "---\ntitle: Now\nlayout: plain\n---\n![Desk](/desk.png) and **now**.",
+	}))
+	if err != nil || len(pages) != 1 {
+		t.Fatalf("GetPages() = %v, %v", pages, err)
+	}
+	if got := pages[0]; got.Layout != "plain" || !strings.Contains(got.Content, "<strong>now</strong>") {
+		t.Errorf("Expected frontmatter and rendered content, got %+v", got)
+	}
+
+	if pages, err := GetPages(filepath.Join(t.TempDir(), "missing")); err != nil || pages != nil {
+		t.Errorf("Expected no pages for a missing directory, got %v, %v", pages, err)
+	}
+}
+
+func TestAddPageNavigation(t *testing.T) {
+	nav := NavigationConfig{
+		Header: []NavItem{{Href: "blog.html", Text: "Blog"}},
+		Footer: []NavItem{{Href: "/uses.html", Text: "My Setup"}},
+	}
+	pages := []Page{
+		{PageFrontmatter: PageFrontmatter{Title: "Now", Nav: []string{"header"}}, Path: "now.html"},
+		{PageFrontmatter: PageFrontmatter{Title: "Uses", Nav: []string{"header", "footer"}}, Path: "uses.html"},
+		{PageFrontmatter: PageFrontmatter{Title: "Colophon"}, Path: "colophon.html"},
+	}
+	AddPageNavigation(&nav, pages)
+
+	wantHeader := []NavItem{{Href: "blog.html", Text: "Blog"}, {Href: "now.html", Text: "Now"}, {Href: "uses.html", Text: "Uses"}}
+	if !reflect.DeepEqual(nav.Header, wantHeader) {
+		t.Errorf("Header = %v, want %v", nav.Header, wantHeader)
+	}
+	wantFooter := []NavItem{{Href: "/uses.html", Text: "My Setup"}}
+	if !reflect.DeepEqual(nav.Footer, wantFooter) {
+		t.Errorf("Footer = %v, want %v", nav.Footer, wantFooter)
+	}
+}
+
+func TestRunPipelineWithPages(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	pageHTML := `{{ define "content" }}{{ range .Config.Navigation.Header }}<a href="{{ relURL .Href }}">{{ .Text }}</a>{{ end }}<main>{{ .Page.Content | safeHTML }}</main>{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "page.html"), []byte(pageHTML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pagesDir := writeTree(t, map[string]string{
+		"uses.md":      "---\ntitle: Uses\nnav: [header]\n---\nMy **tools**.",
+		"notes/now.md": "---\ntitle: Now\n---\nRight now.",
+	})
+
+	out := NewMemoryOutput()
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithOutput(out), WithPagesDir(pagesDir)); err != nil {
+		t.Fatalf("RunPipeline failed: %v", err)
+	}
+
+	site := out.FS(distDir)
+	checks := map[string][]string{
+		"uses.html":      {"<strong>tools</strong>", "<a href=uses.html>Uses</a>"},
+		"notes/now.html": {"Right now.", "<a href=../uses.html>Uses</a>"},
+		"sitemap.xml":    {"https://example.com/uses.html", "https://example.com/notes/now.html"},
+	}
+	for name, wants := range checks {
+		data, err := fs.ReadFile(site, name)
+		if err != nil {
+			t.Errorf("Expected %s in the build: %v", name, err)
+			continue
+		}
+		for _, want := range wants {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Expected %s to contain %q, got %s", name, want, data)
+			}
+		}
+	}
+
+	conflicting := writeTree(t, map[string]string{"about.md": "---\ntitle: About\n---\nAgain."})
+	_, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithOutput(out), WithPagesDir(conflicting))
+	if err == nil || !strings.Contains(err.Error(), "conflicts with a generated page") {
+		t.Errorf("Expected a page replacing a built-in page to fail, got %v", err)
+	}
+}
@@ -1,46 +1,287 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
-// RunPipeline orchestrates the entire site generation flow.
-func RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir string) (int, error) {
-	// 1. Clean and Prepare Dist Directory
-	if err := os.RemoveAll(distDir); err != nil {
-		return 0, fmt.Errorf("failed to clean dist dir: %w", err)
+// PipelineOption customizes a RunPipeline invocation.
+type PipelineOption func(*pipelineConfig)
+
+type pipelineConfig struct {
+	buildTime  time.Time
+	inspector  *BuildInspector
+	output     Output
+	theme      Theme
+	pagesDir   string
+	noCompress bool
+}
+
+// WithBuildTime pins the build clock used for CurrentYear, the manifest's updated_at
+// and sitemap lastmod dates, overriding SOURCE_DATE_EPOCH.
+func WithBuildTime(t time.Time) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.buildTime = t
+	}
+}
+
+// WithInspector records the rendered pages and step timings of the build in i.
+func WithInspector(i *BuildInspector) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.inspector = i
+	}
+}
+
+// WithOutput writes the build to out instead of the filesystem. With a MemoryOutput
+// the finished site is read back with out.FS(distDir) and nothing touches the disk.
+func WithOutput(out Output) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.output = out
+	}
+}
+
+// WithTheme completes the site's templates, content and static files from t.Base and
+// overrides them with the files in t.Dir.
+func WithTheme(t Theme) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.theme = t
+	}
+}
+
+// WithPagesDir renders every Markdown file below dir as a standalone page at the same
+// relative path, such as dir/uses.md at uses.html.
+func WithPagesDir(dir string) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.pagesDir = dir
 	}
-	if err := os.MkdirAll(distDir, 0755); err != nil {
-		return 0, fmt.Errorf("failed to create dist dir: %w", err)
+}
+
+// WithoutCompression skips the .gz and .br variants even when the config enables
+// them, for builds no production server reads, such as the dev server's.
+func WithoutCompression() PipelineOption {
+	return func(c *pipelineConfig) {
+		c.noCompress = true
 	}
+}
 
-	// 2. Load Configuration and Initialize Generator
-	cfg, err := LoadConfig(configDir)
+// SourceDateEpoch parses the SOURCE_DATE_EPOCH environment variable defined by the
+// reproducible-builds specification. It reports false when the variable is unset.
+func SourceDateEpoch() (time.Time, bool, error) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", value, err)
+	}
+	return time.Unix(seconds, 0).UTC(), true, nil
+}
+
+// RunPipeline orchestrates the entire site generation flow. The site is built into a
+// temporary sibling of distDir that only replaces distDir once every step succeeded,
+// so a failed build leaves the previous output untouched.
+func RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir string, opts ...PipelineOption) (int, error) {
+	pc := pipelineConfig{output: DiskOutput{}}
+	epoch, ok, err := SourceDateEpoch()
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		pc.buildTime = epoch
+	}
+	for _, opt := range opts {
+		opt(&pc)
+	}
+
+	phase := time.Now()
+
+	// 1. Load Configuration and Run Pre-Build Hooks
+	cfg, err := LoadConfigFS(pc.theme.Layer(configDir, "contents"))
 	if err != nil {
 		return 0, fmt.Errorf("failed to load config: %w", err)
 	}
+	hooks, err := NewHookRunner(cfg.Hooks)
+	if err != nil {
+		return 0, err
+	}
+	if err := hooks.PreBuild(); err != nil {
+		return 0, err
+	}
+	pc.inspector.lap("pre-build hooks", &phase)
+
+	// 2. Prepare a Fresh Build Directory, then Initialize Generator
+	out := pc.output
+	buildDir, err := out.Stage(distDir)
+	if err != nil {
+		return 0, err
+	}
+	defer out.RemoveAll(buildDir)
 	gen := New(cfg, templatesDir)
+	gen.Templates = pc.theme.Layer(templatesDir, ".")
+	if !pc.buildTime.IsZero() {
+		gen.BuildTime = pc.buildTime
+	}
+	gen.Inspector = pc.inspector
+	gen.Output = out
+
+	static := pc.theme.Layer(publicDir, "static")
+	var parseOpts []ParseOption
+	if cfg.Images.Enabled {
+		gen.Images = NewImageProcessor(cfg.Images, static)
+		parseOpts = append(parseOpts, WithImageProcessor(gen.Images))
+	}
 
 	// 3. Copy Static Assets
-	if _, err := os.Stat(publicDir); err == nil {
-		if err := CopyDir(publicDir, distDir); err != nil {
+	if _, err := fs.Stat(static, "."); err == nil {
+		if err := CopyToOutput(out, static, buildDir); err != nil {
 			log.Printf("Warning: Failed to copy public assets: %v", err)
 		}
 	}
+	if err := gen.MinifyFiles(buildDir); err != nil {
+		return 0, fmt.Errorf("failed to minify static assets: %w", err)
+	}
+	if cfg.Assets.Fingerprint {
+		gen.Assets, err = FingerprintAssets(out, buildDir, cfg.Assets)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fingerprint assets: %w", err)
+		}
+	}
 
-	// 4. Load and Process Content
-	rawPosts, err := GetPosts(blogDir)
-	if err != nil {
-		return 0, fmt.Errorf("failed to load posts: %w", err)
+	pc.inspector.lap("static assets", &phase)
+
+	// 4. Load and Process Content, then Localize Remote Images
+	var data *ContentData
+	entries := 0
+	dirs := cfg.SourceDirs(blogDir)
+	for i, c := range cfg.ContentCollections() {
+		content, err := loadCollection(c, dirs[i], cfg, out, buildDir, parseOpts)
+		if err != nil {
+			return 0, err
+		}
+		entries += len(content.Posts)
+		if data == nil {
+			data = content
+		} else {
+			data.Collections = append(data.Collections, content.Collections...)
+		}
+	}
+	if pc.pagesDir != "" {
+		if data.Pages, err = GetPages(pc.pagesDir, parseOpts...); err != nil {
+			return 0, fmt.Errorf("failed to load pages: %w", err)
+		}
+		AddPageNavigation(&cfg.Navigation, data.Pages)
 	}
-	data := ProcessPosts(rawPosts)
+	pc.inspector.lap("content", &phase)
 
 	// 5. Build Site
-	if err := gen.Build(distDir, data); err != nil {
+	if err := gen.Build(buildDir, data); err != nil {
 		return 0, fmt.Errorf("build failed: %w", err)
 	}
+	pc.inspector.lap("build", &phase)
+
+	// 6. Precompress Outputs
+	if cfg.Compress.Enabled && !pc.noCompress {
+		stats, err := CompressFiles(out, buildDir, cfg.Compress)
+		if err != nil {
+			return 0, err
+		}
+		stats.WriteReport(os.Stdout)
+		pc.inspector.lap("compress", &phase)
+	}
+
+	// 7. Swap the Finished Build into Place
+	if err := out.Publish(buildDir, distDir); err != nil {
+		return 0, err
+	}
+	pc.inspector.lap("swap", &phase)
+
+	// 8. Run Post-Build Hooks
+	if err := hooks.PostBuild(); err != nil {
+		return 0, err
+	}
+	pc.inspector.lap("post-build hooks", &phase)
+
+	return entries, nil
+}
+
+// loadCollection parses the entries of collection c from dir with the site's git
+// history, localizes their remote images and groups them for rendering.
+func loadCollection(c CollectionConfig, dir string, cfg *SiteConfig, out Output, buildDir string, parseOpts []ParseOption) (*ContentData, error) {
+	prefix := pagePathPrefix(c.EntryPath("entry"))
+	parseOpts = append(parseOpts[:len(parseOpts):len(parseOpts)], WithPathPrefix(prefix))
+	if cfg.GitHistory.Enabled {
+		history, err := LoadPostHistory(dir, cfg.GitHistory.Revisions)
+		if err != nil {
+			log.Printf("Warning: Failed to read git history: %v", err)
+		} else {
+			parseOpts = append(parseOpts, WithHistory(history))
+		}
+	}
+
+	posts, err := GetPosts(dir, parseOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s entries: %w", c.Name, err)
+	}
+	if err := LocalizeRenderedImages(out, posts, cfg.RemoteImages, buildDir, prefix); err != nil {
+		return nil, fmt.Errorf("failed to localize remote images: %w", err)
+	}
+	return ProcessCollection(c, posts), nil
+}
 
-	return len(data.Posts), nil
+// newBuildDir creates an empty directory next to distDir for a build in progress.
+func newBuildDir(distDir string) (string, error) {
+	parent := filepath.Dir(distDir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", parent, err)
+	}
+	dir, err := os.MkdirTemp(parent, filepath.Base(distDir)+".build-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create build dir: %w", err)
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to set build dir permissions: %w", err)
+	}
+	return dir, nil
+}
+
+// PrevDir returns where the previous build of distDir is kept after a swap.
+func PrevDir(distDir string) string {
+	return filepath.Clean(distDir) + ".prev"
+}
+
+// swapBuildDir moves the current distDir aside to its .prev sibling, replacing any
+// older one, and renames buildDir into its place. Both renames stay within one parent
+// directory, so distDir always holds one complete build, never a mix of two. Between
+// the renames distDir does not exist for a moment, and a server reading it then finds
+// nothing. If the second rename fails the previous build is moved back, and a failure
+// to do so is reported with the original error.
+func swapBuildDir(buildDir, distDir string) error {
+	prev := PrevDir(distDir)
+	if err := os.RemoveAll(prev); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", prev, err)
+	}
+	kept := true
+	if err := os.Rename(distDir, prev); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to keep previous build: %w", err)
+		}
+		kept = false
+	}
+	if err := os.Rename(buildDir, distDir); err != nil {
+		err = fmt.Errorf("failed to move build into place: %w", err)
+		if kept {
+			if restoreErr := os.Rename(prev, distDir); restoreErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to restore previous build from %s: %w", prev, restoreErr))
+			}
+		}
+		return err
+	}
+	return nil
 }
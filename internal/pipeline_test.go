@@ -1,19 +1,24 @@
 package internal
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestRunPipeline(t *testing.T) {
+// pipelineFixture lays out a minimal site and returns the directories RunPipeline takes.
+func pipelineFixture(t *testing.T) (distDir, configDir, templatesDir, blogDir, publicDir string) {
+	t.Helper()
 	tmpDir := t.TempDir()
 
-	distDir := filepath.Join(tmpDir, "dist")
-	configDir := filepath.Join(tmpDir, "contents")
-	templatesDir := filepath.Join(tmpDir, "templates")
-	blogDir := filepath.Join(tmpDir, "blog")
-	publicDir := filepath.Join(tmpDir, "static")
+	distDir = filepath.Join(tmpDir, "dist")
+	configDir = filepath.Join(tmpDir, "contents")
+	templatesDir = filepath.Join(tmpDir, "templates")
+	blogDir = filepath.Join(tmpDir, "blog")
+	publicDir = filepath.Join(tmpDir, "static")
 
 	// 1. Create dummy configs
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -83,6 +88,11 @@ description: "A test post"
 	if err := os.WriteFile(filepath.Join(publicDir, "test.txt"), []byte("assets"), 0644); err != nil {
 		t.Fatal(err)
 	}
+	return distDir, configDir, templatesDir, blogDir, publicDir
+}
+
+func TestRunPipeline(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
 
 	// 5. Run the pipeline
 	count, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir)
@@ -110,6 +120,7 @@ description: "A test post"
 		filepath.Join("blog", "test.html"),
 		filepath.Join("tags", "integration.html"),
 		filepath.Join("api", "manifest.json"),
+		filepath.Join("api", "schema", "v1", "manifest.json"),
 	}
 
 	for _, output := range expectedOutputs {
@@ -119,3 +130,108 @@ description: "A test post"
 		}
 	}
 }
+
+func TestRunPipelineAtomicSwap(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err != nil {
+		t.Fatalf("First build failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(distDir, "marker.txt"), []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err != nil {
+		t.Fatalf("Second build failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(PrevDir(distDir), "marker.txt")); err != nil {
+		t.Errorf("Expected previous build to be kept in dist.prev: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "marker.txt")); !os.IsNotExist(err) {
+		t.Error("Expected dist to hold only the new build")
+	}
+
+	// A template error must leave the last good build in place.
+	broken := `{{ define "content" }}{{ .Missing.Field }}{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "about.html"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err == nil {
+		t.Fatal("Expected broken template to fail the build")
+	}
+	if _, err := os.Stat(filepath.Join(distDir, "about.html")); err != nil {
+		t.Errorf("Expected last good build to survive a failed build: %v", err)
+	}
+	leftovers, _ := filepath.Glob(distDir + ".build-*")
+	if len(leftovers) != 0 {
+		t.Errorf("Expected temporary build dirs to be removed, got %v", leftovers)
+	}
+}
+
+func TestSwapBuildDirRestoresPrevious(t *testing.T) {
+	root := writeTree(t, map[string]string{"dist/index.html": "old"})
+	distDir := filepath.Join(root, "dist")
+
+	err := swapBuildDir(filepath.Join(root, "missing-build"), distDir)
+	if err == nil || !strings.Contains(err.Error(), "failed to move build into place") {
+		t.Fatalf("swapBuildDir() error = %v, want a failed move", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(distDir, "index.html")); err != nil || string(got) != "old" {
+		t.Errorf("Expected the previous build to be restored, got %q, %v", got, err)
+	}
+}
+
+func TestRunPipelineReproducible(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	t.Setenv("SOURCE_DATE_EPOCH", "1577836800") // 2020-01-01T00:00:00Z
+
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err != nil {
+		t.Fatal(err)
+	}
+	first, err := readSnapshotTree(distDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err != nil {
+		t.Fatal(err)
+	}
+	second, err := readSnapshotTree(distDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != len(second) {
+		t.Fatalf("Expected the same outputs, got %d and %d files", len(first), len(second))
+	}
+	for rel, data := range first {
+		if !bytes.Equal(data, second[rel]) {
+			t.Errorf("%s differs between builds", rel)
+		}
+	}
+	if !bytes.Contains(first["api/manifest.json"], []byte(`"updated_at":"2020-01-01T00:00:00Z"`)) {
+		t.Errorf("Expected manifest to use SOURCE_DATE_EPOCH, got %s", first["api/manifest.json"])
+	}
+	if !bytes.Contains(first["sitemap.xml"], []byte("<lastmod>2020-01-01</lastmod>")) {
+		t.Error("Expected sitemap lastmod to use SOURCE_DATE_EPOCH")
+	}
+
+	t.Run("Option Overrides Environment", func(t *testing.T) {
+		pinned := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithBuildTime(pinned)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(distDir, "api", "manifest.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(`"updated_at":"2021-06-01T00:00:00Z"`)) {
+			t.Errorf("Expected pinned build time, got %s", data)
+		}
+	})
+
+	t.Run("Invalid Epoch", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+		if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir); err == nil {
+			t.Error("Expected invalid SOURCE_DATE_EPOCH to fail the build")
+		}
+	})
+}
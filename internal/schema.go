@@ -2,6 +2,7 @@
 package internal
 
 import (
+	"html/template"
 	"time"
 )
 
@@ -84,6 +85,151 @@ type LandingConfig struct {
 	FocusAreas []string `yaml:"focusAreas"`
 }
 
+// OGImageConfig controls the generated Open Graph preview card rendered for each post.
+type OGImageConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Background      string `yaml:"background"`
+	BackgroundImage string `yaml:"backgroundImage"`
+	Foreground      string `yaml:"foreground"`
+	Accent          string `yaml:"accent"`
+	CacheDir        string `yaml:"cacheDir"`
+}
+
+// ImagesConfig controls resizing of local post and static images into responsive variants.
+type ImagesConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Widths   []int  `yaml:"widths"`
+	Quality  int    `yaml:"quality"`
+	CacheDir string `yaml:"cacheDir"`
+}
+
+// RemoteImagesConfig controls localizing images hosted on third-party servers. Files are
+// stored in StoreDir, recorded in Lockfile, and published under URLPath.
+type RemoteImagesConfig struct {
+	Localize bool   `yaml:"localize"`
+	StoreDir string `yaml:"storeDir"`
+	Lockfile string `yaml:"lockfile"`
+	URLPath  string `yaml:"urlPath"`
+}
+
+// AssetsConfig controls content-hash fingerprinting of static assets. Files matching
+// an Exclude glob keep their stable names; Manifest names the emitted asset manifest.
+type AssetsConfig struct {
+	Fingerprint bool     `yaml:"fingerprint"`
+	Exclude     []string `yaml:"exclude"`
+	Manifest    string   `yaml:"manifest"`
+}
+
+// MinifyConfig toggles minification per output type (html, css, js, json, svg, xml).
+// Types missing from Types are minified.
+type MinifyConfig struct {
+	Types map[string]bool `yaml:"types"`
+}
+
+// Enabled reports whether outputs of the named type should be minified.
+func (c MinifyConfig) Enabled(name string) bool {
+	enabled, ok := c.Types[name]
+	return !ok || enabled
+}
+
+// CompressConfig controls the post-build stage that writes precompressed siblings of
+// text outputs. Formats selects "gzip" and/or "br" (both when empty); files smaller
+// than MinSize bytes or whose extension is not in Extensions are left alone.
+type CompressConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	Formats     []string `yaml:"formats"`
+	MinSize     int      `yaml:"minSize"`
+	GzipLevel   int      `yaml:"gzipLevel"`
+	BrotliLevel int      `yaml:"brotliLevel"`
+	Extensions  []string `yaml:"extensions"`
+}
+
+// HookConfig declares an external command run before or after the build. When Inputs
+// is set, the hook only runs if the files matched by those globs changed since its
+// last successful run or one of its Outputs is missing. Paths in Inputs and Outputs
+// are relative to the project root; Dir sets the command's working directory.
+type HookConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Dir     string            `yaml:"dir"`
+	Env     map[string]string `yaml:"env"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Inputs  []string          `yaml:"inputs"`
+	Outputs []string          `yaml:"outputs"`
+}
+
+// HooksConfig lists the build hooks and where their input hashes are recorded.
+type HooksConfig struct {
+	PreBuild  []HookConfig `yaml:"preBuild"`
+	PostBuild []HookConfig `yaml:"postBuild"`
+	StateFile string       `yaml:"stateFile"`
+}
+
+// GitHistoryConfig enables reading each post's last-modified date and recent
+// revisions from git log. Revisions caps how many commits are kept per post.
+type GitHistoryConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	Revisions int  `yaml:"revisions"`
+}
+
+// SitemapConfig controls sitemap.xml. Types maps a page type (home, page, blog,
+// tag, post) to its priority and change frequency, Exclude lists path.Match globs
+// for pages to leave out, and MaxURLs splits the sitemap behind an index once the
+// page count exceeds it.
+type SitemapConfig struct {
+	Types   map[string]SitemapTypeConfig `yaml:"types"`
+	Exclude []string                     `yaml:"exclude"`
+	MaxURLs int                          `yaml:"maxURLs"`
+}
+
+// SitemapTypeConfig holds the optional sitemap hints for one page type.
+type SitemapTypeConfig struct {
+	Priority   float64 `yaml:"priority"`
+	ChangeFreq string  `yaml:"changefreq"`
+}
+
+// ServeConfig controls the production static server started by `ssg serve`. MaxAge
+// is the cache lifetime of assets that are neither HTML nor fingerprinted.
+type ServeConfig struct {
+	Addr            string        `yaml:"addr"`
+	MaxAge          time.Duration `yaml:"maxAge"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+}
+
+// ServeAddr returns the configured listen address or the default.
+func (c ServeConfig) ServeAddr() string {
+	if c.Addr != "" {
+		return c.Addr
+	}
+	return defaultServeAddr
+}
+
+// CollectionConfig declares a directory of dated Markdown entries rendered like blog
+// posts. URL is the entry path pattern with a {slug} placeholder and TagURL the tag
+// page pattern with a {tag} placeholder; tag pages are only rendered when it is set.
+// Listing pages are rendered with ListTemplate when set: the first at ListURL, later
+// ones next to it as 2.html, 3.html and so on, PageSize entries each (all when zero).
+// Sort orders entries by "date" (newest first, the default), "date-asc" or "title".
+// Feed, Search and Manifest include the entries in rss.xml, the search index and the
+// API manifest. Dir is the source directory, defaulting to a sibling of the blog
+// directory named after the collection.
+type CollectionConfig struct {
+	Name         string `yaml:"name"`
+	Title        string `yaml:"title"`
+	Dir          string `yaml:"dir"`
+	URL          string `yaml:"url"`
+	Template     string `yaml:"template"`
+	ListURL      string `yaml:"listURL"`
+	ListTemplate string `yaml:"listTemplate"`
+	TagURL       string `yaml:"tagURL"`
+	Sort         string `yaml:"sort"`
+	PageSize     int    `yaml:"pageSize"`
+	Feed         bool   `yaml:"feed"`
+	Search       bool   `yaml:"search"`
+	Manifest     bool   `yaml:"manifest"`
+}
+
 // SiteConfig represents the full composite profile parsed from YAML configurations in the repository.
 type SiteConfig struct {
 	Landing       LandingConfig        `yaml:"landing"`
@@ -93,6 +239,17 @@ type SiteConfig struct {
 	Projects      []Project            `yaml:"projects"`
 	Skills        []Skill              `yaml:"skills"`
 	Contributions ContributionsSection `yaml:"contributions"`
+	OGImage       OGImageConfig        `yaml:"ogImage"`
+	Images        ImagesConfig         `yaml:"images"`
+	RemoteImages  RemoteImagesConfig   `yaml:"remoteImages"`
+	Assets        AssetsConfig         `yaml:"assets"`
+	Minify        MinifyConfig         `yaml:"minify"`
+	Compress      CompressConfig       `yaml:"compress"`
+	Hooks         HooksConfig          `yaml:"hooks"`
+	GitHistory    GitHistoryConfig     `yaml:"gitHistory"`
+	Sitemap       SitemapConfig        `yaml:"sitemap"`
+	Serve         ServeConfig          `yaml:"serve"`
+	Collections   []CollectionConfig   `yaml:"collections"`
 }
 
 // ============================================================================
@@ -106,24 +263,81 @@ type Frontmatter struct {
 	Date        time.Time `yaml:"date"`
 	Tags        []string  `yaml:"tags"`
 	Draft       bool      `yaml:"draft"`
+	Updated     time.Time `yaml:"updated"`
 }
 
 // RelatedPost maps target link slugs for displaying behavior-related posts in templates.
 type RelatedPost struct {
 	Title string
 	Slug  string
+	Path  string
 }
 
 // Post encapsulates a full blog item, linking frontmatter metadata with its converted HTML content body.
 type Post struct {
 	Frontmatter
+	Collection   string
+	Path         string
 	Slug         string
 	Content      string
+	WordCount    int
 	RelatedPosts []RelatedPost
+	LastModified time.Time
+	Revisions    []Revision
+}
+
+// Revision is a single commit that touched a post's source file.
+type Revision struct {
+	Hash    string
+	Date    time.Time
+	Subject string
+}
+
+// Modified returns when the post was last changed, falling back to its publish date.
+func (p Post) Modified() time.Time {
+	if p.LastModified.After(p.Date) {
+		return p.LastModified
+	}
+	return p.Date
+}
+
+// IsUpdated reports whether the post was modified on a later day than it was published.
+func (p Post) IsUpdated() bool {
+	return p.Modified().Format("2006-01-02") != p.Date.Format("2006-01-02")
+}
+
+// PageFrontmatter represents metadata defined in the YAML header of standalone Markdown
+// pages. Nav lists the navigation menus, "header" and "footer", the page is added to.
+type PageFrontmatter struct {
+	Title       string    `yaml:"title"`
+	Description string    `yaml:"description"`
+	Layout      string    `yaml:"layout"`
+	Template    string    `yaml:"template"`
+	Nav         []string  `yaml:"nav"`
+	Draft       bool      `yaml:"draft"`
+	Updated     time.Time `yaml:"updated"`
+}
+
+// Page is a standalone Markdown page rendered at Path, its source path relative to the
+// pages directory with an .html extension.
+type Page struct {
+	PageFrontmatter
+	Path    string
+	Content string
+}
+
+// Collection pairs a configured content collection with its loaded entries.
+type Collection struct {
+	CollectionConfig
+	Content *ContentData
 }
 
 // ContentData bundles loaded blog contents, pre-grouped index tables, and tag analytics.
+// Posts and their groupings are those of the first collection; Collections lists every
+// collection, the first included.
 type ContentData struct {
+	Pages        []Page
+	Collections  []Collection
 	Posts        []Post
 	PostsByTag   map[string][]Post
 	PostsByYear  map[int][]Post
@@ -138,24 +352,33 @@ type ContentData struct {
 
 // PageData represents the context contextually applied to HTML templates during site generation.
 type PageData struct {
-	Config       *SiteConfig
-	CurrentYear  int
-	Title        string
-	Posts        []Post
-	Post         *Post
-	Tags         []string
-	TagCounts    map[string]int
-	Archive      map[int][]Post
-	ArchiveYears []int
-	CurrentPage  int
-	TotalPages   int
-	PathPrefix   string
+	Config         *SiteConfig
+	CurrentYear    int
+	Title          string
+	Posts          []Post
+	Post           *Post
+	Page           *Page
+	Collection     *CollectionConfig
+	Tag            string
+	Tags           []string
+	TagCounts      map[string]int
+	Archive        map[int][]Post
+	ArchiveYears   []int
+	CurrentPage    int
+	TotalPages     int
+	PathPrefix     string
+	Path           string
+	Layout         string
+	OGImage        string
+	StructuredData template.JS
 }
 
 // SearchItem maps structure for index searching on the frontend search index payload.
 type SearchItem struct {
 	Title       string   `json:"title"`
 	Slug        string   `json:"slug"`
+	URL         string   `json:"url,omitempty"`
+	Collection  string   `json:"collection,omitempty"`
 	Description string   `json:"description"`
 	Date        string   `json:"date"`
 	Tags        []string `json:"tags"`
@@ -166,6 +389,7 @@ type BlogItem struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description"`
 	URL         string   `json:"url"`
+	Collection  string   `json:"collection,omitempty"`
 	Date        string   `json:"date_published"`
 	Tags        []string `json:"skills"`
 }
@@ -204,13 +428,15 @@ type ProfileRegistry struct {
 }
 
 // Manifest defines the top-level Model Context Protocol (MCP) compatible structure describing the site context.
+// SchemaVersion carries APISchemaVersion so consumers can detect breaking contract changes.
 type Manifest struct {
-	MCPVersion string          `json:"mcp_version"`
-	Name       string          `json:"name"`
-	URL        string          `json:"url"`
-	UpdatedAt  string          `json:"updated_at"`
-	Profile    ProfileRegistry `json:"profile"`
-	Skills     []string        `json:"skills"`
-	Projects   []ProjectItem   `json:"projects"`
-	Blog       BlogRegistry    `json:"blog"`
+	MCPVersion    string          `json:"mcp_version"`
+	SchemaVersion string          `json:"schema_version"`
+	Name          string          `json:"name"`
+	URL           string          `json:"url"`
+	UpdatedAt     string          `json:"updated_at"`
+	Profile       ProfileRegistry `json:"profile"`
+	Skills        []string        `json:"skills"`
+	Projects      []ProjectItem   `json:"projects"`
+	Blog          BlogRegistry    `json:"blog"`
 }
@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultServeAddr            = ":8080"
+	defaultServeMaxAge          = time.Hour
+	defaultServeShutdownTimeout = 10 * time.Second
+
+	// immutableCacheControl lets browsers keep fingerprinted assets for a year without
+	// revalidating, since any change to their content also changes their name.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// revalidateCacheControl makes browsers check HTML with its ETag on every visit.
+	revalidateCacheControl = "no-cache"
+)
+
+// StaticHandler serves a built site from FS, which is the dist directory on disk for
+// `ssg serve` and the in-memory build for the dev server: clean URLs resolve to their
+// .html files, mismatched trailing slashes are redirected, misses get the generated
+// 404.html, and every file carries an ETag,
+// Last-Modified and a Cache-Control suited to whether it is HTML, fingerprinted or
+// neither.
+type StaticHandler struct {
+	FS           fs.FS
+	MaxAge       time.Duration
+	NotFoundPage string
+
+	immutable map[string]bool
+	etags     sync.Map
+}
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	tag     string
+}
+
+// NewStaticHandler serves fsys, treating the files listed in its asset manifest
+// as immutable.
+func NewStaticHandler(fsys fs.FS, cfg ServeConfig, assets AssetsConfig) (*StaticHandler, error) {
+	h := &StaticHandler{
+		FS:           fsys,
+		MaxAge:       cfg.MaxAge,
+		NotFoundPage: "404.html",
+		immutable:    make(map[string]bool),
+	}
+	if h.MaxAge <= 0 {
+		h.MaxAge = defaultServeMaxAge
+	}
+
+	name := assets.Manifest
+	if name == "" {
+		name = defaultAssetManifest
+	}
+	manifest, err := LoadAssetManifest(fsys, name)
+	switch {
+	case err == nil:
+		for _, hashed := range manifest.Assets {
+			h.immutable[hashed] = true
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	return h, nil
+}
+
+// Resolve maps a request path to a file in FS and returns its site-relative path. A
+// path ending in a slash names a directory and resolves to its index.html; any other
+// path resolves to the exact file, or the page with .html appended.
+func (h *StaticHandler) Resolve(urlPath string) (string, bool) {
+	rel := strings.Trim(path.Clean("/"+urlPath), "/")
+	var candidates []string
+	switch {
+	case rel == "":
+		candidates = []string{"index.html"}
+	case strings.HasSuffix(urlPath, "/"):
+		candidates = []string{path.Join(rel, "index.html")}
+	default:
+		candidates = []string{rel}
+		if path.Ext(rel) == "" {
+			candidates = append(candidates, rel+".html")
+		}
+	}
+
+	for _, c := range candidates {
+		if h.isFile(c) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// Redirect returns the URL a request path should be redirected to when its trailing
+// slash does not match what it names: /about/ for about.html loses the slash and
+// /docs for docs/index.html gains one. Pages link relatively, so they only work when
+// requested at the depth they were rendered for.
+func (h *StaticHandler) Redirect(urlPath string) (string, bool) {
+	rel := strings.Trim(path.Clean("/"+urlPath), "/")
+	if rel == "" {
+		return "", false
+	}
+	page := path.Ext(rel) == "" && h.isFile(rel+".html")
+	index := h.isFile(path.Join(rel, "index.html"))
+	switch {
+	case strings.HasSuffix(urlPath, "/") && page && !index:
+		return "/" + rel, true
+	case !strings.HasSuffix(urlPath, "/") && index && !page && !h.isFile(rel):
+		return "/" + rel + "/", true
+	}
+	return "", false
+}
+
+func (h *StaticHandler) isFile(name string) bool {
+	info, err := fs.Stat(h.FS, name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if target, ok := h.Redirect(r.URL.Path); ok {
+		RedirectTo(w, r, target)
+		return
+	}
+	rel, ok := h.Resolve(r.URL.Path)
+	if !ok {
+		h.serveNotFound(w, r)
+		return
+	}
+
+	switch {
+	case path.Ext(rel) == ".html":
+		w.Header().Set("Cache-Control", revalidateCacheControl)
+	case h.immutable[rel]:
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	default:
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.MaxAge.Seconds())))
+	}
+	h.ServeFile(w, r, rel)
+}
+
+// RedirectTo permanently redirects r to target, keeping its query string.
+func RedirectTo(w http.ResponseWriter, r *http.Request, target string) {
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// serveNotFound answers with the site's 404 page, or a plain message when the
+// build has none.
+func (h *StaticHandler) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	data, err := fs.ReadFile(h.FS, h.NotFoundPage)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", revalidateCacheControl)
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// ServeFile serves the file p of FS, preferring a precompressed .br or .gz sibling
+// when the client accepts it. The Content-Type is taken from the original file's
+// extension, and the ETag is the content hash of the bytes actually sent so each
+// encoding has its own.
+func (h *StaticHandler) ServeFile(w http.ResponseWriter, r *http.Request, p string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	served := p
+	variant, encoding := PrecompressedVariant(h.FS, p, r.Header.Get("Accept-Encoding"))
+	if encoding != "" {
+		served = variant
+	}
+
+	f, err := h.FS.Open(served)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not seekable", served), http.StatusInternalServerError)
+		return
+	}
+
+	tag, err := h.etag(served, info, content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	if ctype := mime.TypeByExtension(path.Ext(p)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	http.ServeContent(w, r, p, info.ModTime(), content)
+}
+
+// etag returns the cached content hash of a file, recomputing it when the file's
+// size or modification time changed. f is rewound afterwards.
+func (h *StaticHandler) etag(p string, info fs.FileInfo, f io.ReadSeeker) (string, error) {
+	if v, ok := h.etags.Load(p); ok {
+		e := v.(etagEntry)
+		if e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+			return e.tag, nil
+		}
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", p, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	tag := `"` + hex.EncodeToString(sum.Sum(nil))[:16] + `"`
+	h.etags.Store(p, etagEntry{modTime: info.ModTime(), size: info.Size(), tag: tag})
+	return tag, nil
+}
+
+// RunServer serves srv until ctx is cancelled, then shuts it down gracefully,
+// giving in-flight requests up to timeout to finish.
+func RunServer(ctx context.Context, srv *http.Server, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultServeShutdownTimeout
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down (waiting up to %v for open requests)...", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
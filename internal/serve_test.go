@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStaticHandler serves a small site from disk and returns the handler and
+// the directory it serves.
+func newTestStaticHandler(t *testing.T) (*StaticHandler, string) {
+	t.Helper()
+	root := writeTree(t, map[string]string{
+		"index.html":          "<h1>Home</h1>",
+		"404.html":            "<h1>Missing</h1>",
+		"blog.html":           "<h1>Blog</h1>",
+		"blog/2.html":         "<h1>Page 2</h1>",
+		"blog/hello.html":     "<h1>Hello</h1>",
+		"docs/index.html":     "<h1>Docs</h1>",
+		"styles.1a2b3c4d.css": "body{}",
+		"robots.txt":          "User-agent: *",
+		"app.js":              "console.log(1)",
+		"app.js.br":           "brotli-bytes",
+		"asset-manifest.json": `{"styles.css": "styles.1a2b3c4d.css"}`,
+	})
+	if err := os.WriteFile(filepath.Join(filepath.Dir(root), "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewStaticHandler(os.DirFS(root), ServeConfig{MaxAge: 2 * time.Hour}, AssetsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, root
+}
+
+func TestStaticHandler(t *testing.T) {
+	h, _ := newTestStaticHandler(t)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		encoding     string
+		wantStatus   int
+		wantBody     string
+		wantCache    string
+		wantEncoding string
+		wantLocation string
+	}{
+		{name: "Root Index", path: "/", wantStatus: 200, wantBody: "Home", wantCache: "no-cache"},
+		{name: "Clean URL", path: "/blog/hello", wantStatus: 200, wantBody: "Hello", wantCache: "no-cache"},
+		{name: "File Preferred Over Directory", path: "/blog", wantStatus: 200, wantBody: "Blog"},
+		{name: "Directory Index", path: "/docs/", wantStatus: 200, wantBody: "Docs"},
+		{name: "Directory Without Slash", path: "/docs?q=1", wantStatus: 301, wantLocation: "/docs/?q=1"},
+		{name: "Page With Slash", path: "/blog/", wantStatus: 301, wantLocation: "/blog"},
+		{name: "File With Slash", path: "/robots.txt/", wantStatus: 404, wantBody: "Missing"},
+		{name: "Explicit HTML", path: "/blog/2.html", wantStatus: 200, wantBody: "Page 2"},
+		{name: "Missing Page", path: "/nope", wantStatus: 404, wantBody: "Missing", wantCache: "no-cache"},
+		{name: "Traversal Stays In Root", path: "/../secret.txt", wantStatus: 404, wantBody: "Missing"},
+		{name: "Fingerprinted Asset", path: "/styles.1a2b3c4d.css", wantStatus: 200, wantCache: immutableCacheControl},
+		{name: "Plain Asset", path: "/robots.txt", wantStatus: 200, wantCache: "public, max-age=7200"},
+		{name: "Precompressed", path: "/app.js", encoding: "gzip, br", wantStatus: 200, wantBody: "brotli-bytes", wantEncoding: "br"},
+		{name: "Method Not Allowed", method: http.MethodPost, path: "/", wantStatus: 405},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.encoding != "" {
+				req.Header.Set("Accept-Encoding", tt.encoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("Body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantCache != "" && rec.Header().Get("Cache-Control") != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", rec.Header().Get("Cache-Control"), tt.wantCache)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestStaticHandlerConditionalRequests(t *testing.T) {
+	h, root := newTestStaticHandler(t)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/app.js", nil)
+	etag := first.Header().Get("ETag")
+	if etag == "" || first.Header().Get("Last-Modified") == "" {
+		t.Fatalf("Expected ETag and Last-Modified, got %v", first.Header())
+	}
+	if got := get("/app.js", map[string]string{"If-None-Match": etag}); got.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for matching ETag, got %d", got.Code)
+	}
+
+	br := get("/app.js", map[string]string{"Accept-Encoding": "br"})
+	if br.Header().Get("ETag") == etag {
+		t.Error("Expected precompressed variant to have its own ETag")
+	}
+	if br.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+		t.Errorf("Expected Content-Type from original extension, got %q", br.Header().Get("Content-Type"))
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "app.js"), []byte("console.log(2)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed := get("/app.js", nil); changed.Header().Get("ETag") == etag {
+		t.Error("Expected ETag to change with the file contents")
+	}
+}
+
+func TestRunServerGracefulShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	started := make(chan struct{})
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("done"))
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunServer(ctx, srv, 5*time.Second) }()
+
+	resCh := make(chan string, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			res, err := http.Get("http://" + addr + "/")
+			if err != nil {
+				time.Sleep(20 * time.Millisecond)
+				continue
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			resCh <- string(body)
+			return
+		}
+		resCh <- "unreachable"
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server never received the request")
+	}
+	cancel()
+
+	if body := <-resCh; body != "done" {
+		t.Errorf("Expected in-flight request to finish, got %q", body)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunServer() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunServer did not return after cancellation")
+	}
+}
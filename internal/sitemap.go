@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sitemapMaxURLs is the protocol limit on URLs per sitemap file.
+	sitemapMaxURLs = 50000
+	sitemapXMLNS   = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapImageNS = "http://www.google.com/schemas/sitemap-image/1.1"
+)
+
+// defaultSitemapExcludes keeps pages search engines should not index out of the
+// sitemap when the config lists no excludes.
+var defaultSitemapExcludes = []string{"404.html"}
+
+var sitemapImgPattern = regexp.MustCompile(`<img\b[^>]*?\bsrc="([^"]+)"`)
+
+// SitemapEntry is one page recorded by RenderPage for the sitemap.
+type SitemapEntry struct {
+	Path    string
+	Type    string
+	LastMod time.Time
+	Images  []string
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	ImageNS string       `xml:"xmlns:image,attr,omitempty"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string         `xml:"loc"`
+	LastMod    string         `xml:"lastmod,omitempty"`
+	ChangeFreq string         `xml:"changefreq,omitempty"`
+	Priority   string         `xml:"priority,omitempty"`
+	Images     []sitemapImage `xml:"image:image"`
+}
+
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// pageType classifies a rendered page for the per-type sitemap settings. Entries of
+// every collection are posts, and their listing pages count as blog pages.
+func pageType(data PageData) string {
+	switch {
+	case data.Post != nil:
+		return "post"
+	case data.Path == "index.html":
+		return "home"
+	case data.Tag != "" || strings.HasPrefix(data.Path, "tags/"):
+		return "tag"
+	case data.Collection != nil || data.Path == "blog.html" || strings.HasPrefix(data.Path, "blog/"):
+		return "blog"
+	default:
+		return "page"
+	}
+}
+
+// recordPage registers a page written by RenderPage. Post pages use the post's
+// modified date, listings their newest post and everything else the build time.
+func (g *SiteGenerator) recordPage(data PageData) {
+	entry := SitemapEntry{Path: data.Path, Type: pageType(data), LastMod: g.BuildTime}
+	switch {
+	case data.Post != nil:
+		entry.LastMod = data.Post.Modified()
+	case data.Page != nil && !data.Page.Updated.IsZero():
+		entry.LastMod = data.Page.Updated
+	case len(data.Posts) > 0:
+		entry.LastMod = time.Time{}
+		for _, p := range data.Posts {
+			if p.Modified().After(entry.LastMod) {
+				entry.LastMod = p.Modified()
+			}
+		}
+	}
+
+	if data.OGImage != "" {
+		entry.Images = append(entry.Images, data.OGImage)
+	}
+	if data.Post != nil {
+		entry.Images = append(entry.Images, g.contentImages(data.Path, data.Post.Content)...)
+	}
+	if data.Page != nil {
+		entry.Images = append(entry.Images, g.contentImages(data.Path, data.Page.Content)...)
+	}
+
+	if g.pages == nil {
+		g.pages = make(map[string]SitemapEntry)
+	}
+	g.pages[data.Path] = entry
+}
+
+// contentImages returns the absolute URLs of the images embedded in a page's HTML,
+// resolving relative sources against the page URL and skipping data URIs.
+func (g *SiteGenerator) contentImages(pagePath, content string) []string {
+	base, err := url.Parse(g.Config.Landing.URL + pagePath)
+	if err != nil {
+		return nil
+	}
+	var images []string
+	seen := make(map[string]bool)
+	for _, m := range sitemapImgPattern.FindAllStringSubmatch(content, -1) {
+		ref, err := url.Parse(html.UnescapeString(m[1]))
+		if err != nil || ref.Scheme == "data" {
+			continue
+		}
+		abs := base.ResolveReference(ref).String()
+		if !seen[abs] {
+			seen[abs] = true
+			images = append(images, abs)
+		}
+	}
+	return images
+}
+
+// Pages returns the recorded sitemap entries sorted by path.
+func (g *SiteGenerator) Pages() []SitemapEntry {
+	entries := make([]SitemapEntry, 0, len(g.pages))
+	for _, e := range g.pages {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// sitemapLoc returns the absolute URL of a page, serving index.html as the site root.
+func (g *SiteGenerator) sitemapLoc(p string) string {
+	if p == "index.html" {
+		p = ""
+	}
+	return g.Config.Landing.URL + (&url.URL{Path: p}).EscapedPath()
+}
+
+// GenerateSitemap writes sitemap.xml from every page rendered so far plus the API
+// manifest. Past SitemapConfig.MaxURLs entries it writes numbered sitemap-N.xml
+// files and turns sitemap.xml into an index of them.
+func (g *SiteGenerator) GenerateSitemap(distDir string) error {
+	cfg := g.Config.Sitemap
+	excludes := cfg.Exclude
+	if excludes == nil {
+		excludes = defaultSitemapExcludes
+	}
+
+	entries := []SitemapEntry{{Path: "api/manifest.json", Type: "api", LastMod: g.BuildTime}}
+	for _, e := range g.Pages() {
+		if !excludedAsset(e.Path, excludes) {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return g.sitemapLoc(entries[i].Path) < g.sitemapLoc(entries[j].Path)
+	})
+
+	var urls []sitemapURL
+	for _, e := range entries {
+		u := sitemapURL{
+			Loc:     g.sitemapLoc(e.Path),
+			LastMod: e.LastMod.Format("2006-01-02"),
+		}
+		if t, ok := cfg.Types[e.Type]; ok {
+			u.ChangeFreq = t.ChangeFreq
+			if t.Priority > 0 {
+				u.Priority = strconv.FormatFloat(t.Priority, 'f', 1, 64)
+			}
+		}
+		for _, img := range e.Images {
+			u.Images = append(u.Images, sitemapImage{Loc: img})
+		}
+		urls = append(urls, u)
+	}
+
+	maxURLs := cfg.MaxURLs
+	if maxURLs <= 0 || maxURLs > sitemapMaxURLs {
+		maxURLs = sitemapMaxURLs
+	}
+	if len(urls) <= maxURLs {
+		return writeSitemapXML(g.Output, filepath.Join(distDir, "sitemap.xml"), newURLSet(urls))
+	}
+
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	for i := 0; i*maxURLs < len(urls); i++ {
+		chunk := urls[i*maxURLs : min((i+1)*maxURLs, len(urls))]
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if err := writeSitemapXML(g.Output, filepath.Join(distDir, name), newURLSet(chunk)); err != nil {
+			return err
+		}
+		lastMod := ""
+		for _, u := range chunk {
+			if u.LastMod > lastMod {
+				lastMod = u.LastMod
+			}
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapRef{Loc: g.Config.Landing.URL + name, LastMod: lastMod})
+	}
+	return writeSitemapXML(g.Output, filepath.Join(distDir, "sitemap.xml"), index)
+}
+
+// newURLSet declares the image namespace only when an entry uses it.
+func newURLSet(urls []sitemapURL) sitemapURLSet {
+	set := sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls}
+	for _, u := range urls {
+		if len(u.Images) > 0 {
+			set.ImageNS = sitemapImageNS
+			break
+		}
+	}
+	return set
+}
+
+func writeSitemapXML(out Output, p string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(p), err)
+	}
+	if err := out.WriteFile(p, append([]byte(xml.Header), data...)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(p), err)
+	}
+	return nil
+}
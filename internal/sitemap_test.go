@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageType(t *testing.T) {
+	post := &Post{}
+	tests := []struct {
+		data PageData
+		want string
+	}{
+		{PageData{Path: "index.html"}, "home"},
+		{PageData{Path: "about.html"}, "page"},
+		{PageData{Path: "blog.html"}, "blog"},
+		{PageData{Path: "blog/2.html"}, "blog"},
+		{PageData{Path: "blog/hello.html", Post: post}, "post"},
+		{PageData{Path: "tags/go.html"}, "tag"},
+	}
+	for _, tt := range tests {
+		if got := pageType(tt.data); got != tt.want {
+			t.Errorf("pageType(%s) = %s, want %s", tt.data.Path, got, tt.want)
+		}
+	}
+}
+
+func newSitemapGenerator(t *testing.T, cfg SitemapConfig) (*SiteGenerator, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	createTemplates(t, tmpDir)
+	siteCfg := createConfig()
+	siteCfg.Sitemap = cfg
+	gen := New(siteCfg, filepath.Join(tmpDir, "internal", "templates"))
+	gen.BuildTime = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	distDir := filepath.Join(tmpDir, "dist")
+
+	older := Post{Frontmatter: Frontmatter{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, Slug: "older"}
+	edited := Post{
+		Frontmatter:  Frontmatter{Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		Slug:         "edited",
+		Content:      `<p><img src="../images/a.png?w=1&amp;h=2"><img src="https://cdn.example.org/b.png"><img src="data:image/png;base64,AA"></p>`,
+		LastModified: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	pages := []struct {
+		dir, file, tmpl string
+		data            PageData
+	}{
+		{distDir, "index.html", "index.html", PageData{Path: "index.html"}},
+		{distDir, "404.html", "404.html", PageData{Path: "404.html"}},
+		{distDir, "blog.html", "blog.html", PageData{Path: "blog.html", Posts: []Post{edited, older}}},
+		{filepath.Join(distDir, "tags"), "c&d.html", "blog.html", PageData{Path: "tags/c&d.html", Posts: []Post{older}}},
+		{filepath.Join(distDir, "blog"), "edited.html", "post.html", PageData{Path: "blog/edited.html", Post: &edited, OGImage: "http://example.com/og/edited.png"}},
+	}
+	for _, p := range pages {
+		if err := gen.RenderPage(p.dir, p.file, p.tmpl, "", p.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return gen, distDir
+}
+
+func TestGenerateSitemap(t *testing.T) {
+	gen, distDir := newSitemapGenerator(t, SitemapConfig{
+		Types: map[string]SitemapTypeConfig{
+			"home": {Priority: 1, ChangeFreq: "weekly"},
+			"post": {Priority: 0.8},
+		},
+	})
+	if err := gen.GenerateSitemap(distDir); err != nil {
+		t.Fatalf("GenerateSitemap() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(distDir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `<loc>http://example.com/tags/c&amp;d.html</loc>`) {
+		t.Errorf("Expected escaped tag URL, got:\n%s", raw)
+	}
+	if !strings.Contains(string(raw), `xmlns:image="`+sitemapImageNS+`"`) {
+		t.Errorf("Expected image namespace, got:\n%s", raw)
+	}
+
+	var set struct {
+		URLs []struct {
+			Loc        string   `xml:"loc"`
+			LastMod    string   `xml:"lastmod"`
+			ChangeFreq string   `xml:"changefreq"`
+			Priority   string   `xml:"priority"`
+			Images     []string `xml:"image>loc"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal(raw, &set); err != nil {
+		t.Fatalf("Invalid sitemap XML: %v", err)
+	}
+
+	var locs []string
+	byLoc := make(map[string]int)
+	for i, u := range set.URLs {
+		locs = append(locs, u.Loc)
+		byLoc[u.Loc] = i
+	}
+	wantLocs := []string{
+		"http://example.com/",
+		"http://example.com/api/manifest.json",
+		"http://example.com/blog.html",
+		"http://example.com/blog/edited.html",
+		"http://example.com/tags/c&d.html",
+	}
+	if !reflect.DeepEqual(locs, wantLocs) {
+		t.Fatalf("Sitemap URLs = %v, want %v", locs, wantLocs)
+	}
+
+	home := set.URLs[byLoc["http://example.com/"]]
+	if home.Priority != "1.0" || home.ChangeFreq != "weekly" || home.LastMod != "2024-07-01" {
+		t.Errorf("Unexpected home entry %+v", home)
+	}
+	if got := set.URLs[byLoc["http://example.com/blog.html"]].LastMod; got != "2024-05-01" {
+		t.Errorf("Expected listing lastmod from newest post edit, got %s", got)
+	}
+	post := set.URLs[byLoc["http://example.com/blog/edited.html"]]
+	if post.LastMod != "2024-05-01" || post.Priority != "0.8" || post.ChangeFreq != "" {
+		t.Errorf("Unexpected post entry %+v", post)
+	}
+	wantImages := []string{
+		"http://example.com/og/edited.png",
+		"http://example.com/images/a.png?w=1&h=2",
+		"https://cdn.example.org/b.png",
+	}
+	if !reflect.DeepEqual(post.Images, wantImages) {
+		t.Errorf("Post images = %v, want %v", post.Images, wantImages)
+	}
+}
+
+func TestGenerateSitemapIndex(t *testing.T) {
+	gen, distDir := newSitemapGenerator(t, SitemapConfig{MaxURLs: 2, Exclude: []string{}})
+	if err := gen.GenerateSitemap(distDir); err != nil {
+		t.Fatalf("GenerateSitemap() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(distDir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		XMLName  xml.Name
+		Sitemaps []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(raw, &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.XMLName.Local != "sitemapindex" || len(index.Sitemaps) != 3 {
+		t.Fatalf("Expected an index of 3 sitemaps (6 URLs including 404.html), got:\n%s", raw)
+	}
+	for i, s := range index.Sitemaps {
+		name := filepath.Base(s.Loc)
+		if want := "sitemap-" + string(rune('1'+i)) + ".xml"; name != want {
+			t.Errorf("Sitemap %d = %s, want %s", i, name, want)
+		}
+		if _, err := os.Stat(filepath.Join(distDir, name)); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Normalizer rewrites the volatile parts of a generated file, such as build
+// timestamps, before it is compared against a golden snapshot.
+type Normalizer func(rel string, data []byte) []byte
+
+// RegexpNormalizer replaces every match of pattern with replacement in files whose
+// extension is one of exts, or in every file when exts is empty.
+func RegexpNormalizer(pattern, replacement string, exts ...string) Normalizer {
+	re := regexp.MustCompile(pattern)
+	return func(rel string, data []byte) []byte {
+		if len(exts) > 0 {
+			ext := path.Ext(rel)
+			matched := false
+			for _, e := range exts {
+				matched = matched || e == ext
+			}
+			if !matched {
+				return data
+			}
+		}
+		return re.ReplaceAll(data, []byte(replacement))
+	}
+}
+
+// BuildTimeNormalizers mask the values that depend on when the site was built: the
+// footer's CurrentYear and the manifest's updated_at. Sitemap lastmod dates are left
+// alone because most come from post dates; pin the build clock with WithBuildTime to
+// make the rest reproducible.
+var BuildTimeNormalizers = []Normalizer{
+	RegexpNormalizer(`(©|&copy;) \d{4}`, "$1 YYYY", ".html"),
+	RegexpNormalizer(`("updated_at":\s*)"[^"]*"`, `$1"UPDATED_AT"`, ".json"),
+}
+
+// readSnapshotTree reads every file under dir, keyed by slash-separated relative path,
+// applying normalizers to each.
+func readSnapshotTree(dir string, normalizers []Normalizer) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for _, normalize := range normalizers {
+			data = normalize(rel, data)
+		}
+		files[rel] = data
+		return nil
+	})
+	return files, err
+}
+
+// CompareSnapshots checks every file in outDir against its golden copy in goldenDir
+// after normalization. It returns one message per missing, unexpected or differing
+// file, with a readable diff for text outputs.
+func CompareSnapshots(outDir, goldenDir string, normalizers []Normalizer) ([]string, error) {
+	got, err := readSnapshotTree(outDir, normalizers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outputs: %w", err)
+	}
+	want, err := readSnapshotTree(goldenDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden files: %w", err)
+	}
+
+	var mismatches []string
+	for rel, data := range got {
+		golden, ok := want[rel]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s: no golden file", rel))
+		case !bytes.Equal(data, golden):
+			fd, _ := diffFile(rel, golden, data, nil)
+			mismatches = append(mismatches, fmt.Sprintf("%s: differs from golden file\n%s", rel, strings.Join(fd.Lines, "\n")))
+		}
+	}
+	for rel := range want {
+		if _, ok := got[rel]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: golden file exists but was not generated", rel))
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches, nil
+}
+
+// UpdateSnapshots replaces goldenDir with the normalized contents of outDir.
+func UpdateSnapshots(outDir, goldenDir string, normalizers []Normalizer) error {
+	files, err := readSnapshotTree(outDir, normalizers)
+	if err != nil {
+		return fmt.Errorf("failed to read outputs: %w", err)
+	}
+	if err := os.RemoveAll(goldenDir); err != nil {
+		return fmt.Errorf("failed to clear golden files: %w", err)
+	}
+	for rel, data := range files {
+		p := filepath.Join(goldenDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return fmt.Errorf("failed to create golden dir: %w", err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			return fmt.Errorf("failed to write golden file %s: %w", rel, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update regenerates golden files: go test ./internal -run TestGoldenSnapshots -update
+var update = flag.Bool("update", false, "regenerate golden snapshot files")
+
+const (
+	snapshotFixtureDir = "testdata/snapshot"
+	snapshotGoldenDir  = "testdata/snapshot/golden"
+)
+
+// snapshotBuildTime pins the clock so build-time sitemap dates are reproducible
+// while post dates are still compared.
+var snapshotBuildTime = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestGoldenSnapshots(t *testing.T) {
+	distDir := filepath.Join(t.TempDir(), "dist")
+	_, err := RunPipeline(
+		distDir,
+		filepath.Join(snapshotFixtureDir, "contents"),
+		"templates",
+		filepath.Join(snapshotFixtureDir, "blog"),
+		filepath.Join(snapshotFixtureDir, "static"),
+		WithBuildTime(snapshotBuildTime),
+	)
+	if err != nil {
+		t.Fatalf("RunPipeline() error = %v", err)
+	}
+
+	if *update {
+		if err := UpdateSnapshots(distDir, snapshotGoldenDir, BuildTimeNormalizers); err != nil {
+			t.Fatal(err)
+		}
+		t.Log("Updated golden files in " + snapshotGoldenDir)
+		return
+	}
+
+	mismatches, err := CompareSnapshots(distDir, snapshotGoldenDir, BuildTimeNormalizers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Error(m)
+	}
+	if len(mismatches) > 0 {
+		t.Log("If the changes are intended, rerun with -update to regenerate the golden files.")
+	}
+}
+
+func TestBuildTimeNormalizers(t *testing.T) {
+	tests := []struct {
+		rel  string
+		in   string
+		want string
+	}{
+		{"index.html", "<p>© 2026 Site", "<p>© YYYY Site"},
+		{"index.html", "<p>&copy; 2026 Site", "<p>&copy; YYYY Site"},
+		{"api/manifest.json", `{"updated_at":"2026-01-02T03:04:05Z"}`, `{"updated_at":"UPDATED_AT"}`},
+		{"sitemap.xml", "<lastmod>2026-01-02</lastmod>", "<lastmod>2026-01-02</lastmod>"},
+	}
+	for _, tt := range tests {
+		got := []byte(tt.in)
+		for _, normalize := range BuildTimeNormalizers {
+			got = normalize(tt.rel, got)
+		}
+		if string(got) != tt.want {
+			t.Errorf("normalize(%s, %q) = %q, want %q", tt.rel, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	golden := writeTree(t, map[string]string{"a.html": "<p>one</p>", "gone.txt": "x"})
+	out := writeTree(t, map[string]string{"a.html": "<p>two</p>", "new.txt": "y"})
+
+	mismatches, err := CompareSnapshots(out, golden, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(mismatches, "\n")
+	for _, want := range []string{"a.html: differs", "-one\n+two", "gone.txt: golden file exists", "new.txt: no golden file"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected mismatch %q, got:\n%s", want, joined)
+		}
+	}
+
+	if err := UpdateSnapshots(out, golden, nil); err != nil {
+		t.Fatal(err)
+	}
+	if mismatches, _ := CompareSnapshots(out, golden, nil); len(mismatches) != 0 {
+		t.Errorf("Expected no mismatches after update, got %v", mismatches)
+	}
+}
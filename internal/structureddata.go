@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// schema.org JSON-LD Types
+// ============================================================================
+
+// LDRef links to another node in the same JSON-LD graph by its @id.
+type LDRef struct {
+	ID string `json:"@id"`
+}
+
+// LDPerson describes the site author as a schema.org Person.
+type LDPerson struct {
+	Type        string   `json:"@type"`
+	ID          string   `json:"@id"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	KnowsAbout  []string `json:"knowsAbout,omitempty"`
+	SameAs      []string `json:"sameAs,omitempty"`
+}
+
+// LDWebSite describes the site itself as a schema.org WebSite.
+type LDWebSite struct {
+	Type        string `json:"@type"`
+	ID          string `json:"@id"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	InLanguage  string `json:"inLanguage"`
+	Author      LDRef  `json:"author"`
+}
+
+// LDBlogPosting describes a single post as a schema.org BlogPosting.
+type LDBlogPosting struct {
+	Type             string `json:"@type"`
+	ID               string `json:"@id"`
+	Headline         string `json:"headline"`
+	Description      string `json:"description,omitempty"`
+	Keywords         string `json:"keywords,omitempty"`
+	DatePublished    string `json:"datePublished"`
+	DateModified     string `json:"dateModified"`
+	WordCount        int    `json:"wordCount"`
+	URL              string `json:"url"`
+	Image            string `json:"image,omitempty"`
+	MainEntityOfPage string `json:"mainEntityOfPage"`
+	Author           LDRef  `json:"author"`
+	Publisher        LDRef  `json:"publisher"`
+	IsPartOf         LDRef  `json:"isPartOf"`
+}
+
+// LDListItem is a single step of a BreadcrumbList.
+type LDListItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	Item     string `json:"item"`
+}
+
+// LDBreadcrumbList describes the navigation trail leading to a page.
+type LDBreadcrumbList struct {
+	Type            string       `json:"@type"`
+	ItemListElement []LDListItem `json:"itemListElement"`
+}
+
+// LDGraph bundles every node emitted for a page into one JSON-LD document.
+type LDGraph struct {
+	Context string        `json:"@context"`
+	Graph   []interface{} `json:"@graph"`
+}
+
+// ============================================================================
+// Builders
+// ============================================================================
+
+// BuildPerson describes the site author from the landing profile and external socials.
+func BuildPerson(cfg *SiteConfig) LDPerson {
+	var sameAs []string
+	for _, s := range cfg.Socials {
+		if strings.HasPrefix(s.Href, "http") {
+			sameAs = append(sameAs, s.Href)
+		}
+	}
+	var image string
+	if cfg.About.Image != "" {
+		image = cfg.Landing.URL + cfg.About.Image
+	}
+	return LDPerson{
+		Type:        "Person",
+		ID:          cfg.Landing.URL + "#person",
+		Name:        cfg.Landing.Name,
+		URL:         cfg.Landing.URL,
+		Description: cfg.Landing.Slogan,
+		Image:       image,
+		KnowsAbout:  cfg.Landing.FocusAreas,
+		SameAs:      sameAs,
+	}
+}
+
+// BuildWebSite describes the site, attributed to the Person node.
+func BuildWebSite(cfg *SiteConfig) LDWebSite {
+	return LDWebSite{
+		Type:        "WebSite",
+		ID:          cfg.Landing.URL + "#website",
+		Name:        cfg.Landing.Title,
+		URL:         cfg.Landing.URL,
+		Description: cfg.Landing.Slogan,
+		InLanguage:  "en-US",
+		Author:      LDRef{ID: cfg.Landing.URL + "#person"},
+	}
+}
+
+// BuildBlogPosting describes post, using image as its preview picture. Posts loaded
+// outside a collection are assumed to live in the blog.
+func BuildBlogPosting(cfg *SiteConfig, post *Post, image string) LDBlogPosting {
+	pagePath := post.Path
+	if pagePath == "" {
+		pagePath = DefaultCollection().EntryPath(post.Slug)
+	}
+	url := cfg.Landing.URL + pagePath
+	return LDBlogPosting{
+		Type:             "BlogPosting",
+		ID:               url + "#article",
+		Headline:         post.Title,
+		Description:      post.Description,
+		Keywords:         strings.Join(post.Tags, ", "),
+		DatePublished:    post.Date.Format(time.RFC3339),
+		DateModified:     post.Modified().Format(time.RFC3339),
+		WordCount:        post.WordCount,
+		URL:              url,
+		Image:            image,
+		MainEntityOfPage: url,
+		Author:           LDRef{ID: cfg.Landing.URL + "#person"},
+		Publisher:        LDRef{ID: cfg.Landing.URL + "#person"},
+		IsPartOf:         LDRef{ID: cfg.Landing.URL + "#website"},
+	}
+}
+
+// BuildBreadcrumbs returns the trail from the home page to the page at path, labelled
+// name. Entries, later listing pages and tag pages of a collection with a listing, such
+// as those under blog/ and tags/, are nested beneath its first listing page.
+func BuildBreadcrumbs(cfg *SiteConfig, path, name string) *LDBreadcrumbList {
+	if path == "" || path == "index.html" {
+		return nil
+	}
+
+	items := []LDListItem{{Type: "ListItem", Position: 1, Name: cfg.Landing.Title, Item: cfg.Landing.URL}}
+	for _, c := range cfg.ContentCollections() {
+		if c.ListTemplate != "" && path != c.ListURL && c.owns(path) {
+			items = append(items, LDListItem{Type: "ListItem", Position: 2, Name: c.Title, Item: cfg.Landing.URL + c.ListURL})
+			break
+		}
+	}
+	items = append(items, LDListItem{Type: "ListItem", Position: len(items) + 1, Name: name, Item: cfg.Landing.URL + path})
+
+	return &LDBreadcrumbList{Type: "BreadcrumbList", ItemListElement: items}
+}
+
+// structuredData encodes the JSON-LD graph for a page. json.Marshal escapes <, > and &,
+// so the result is safe to embed verbatim inside a <script> element.
+func (g *SiteGenerator) structuredData(data PageData, name string) (template.JS, error) {
+	graph := LDGraph{
+		Context: "https://schema.org",
+		Graph:   []interface{}{BuildWebSite(g.Config), BuildPerson(g.Config)},
+	}
+
+	if data.Post != nil {
+		image := data.OGImage
+		if image == "" && g.Config.About.Image != "" {
+			image = g.Config.Landing.URL + g.Config.About.Image
+		}
+		graph.Graph = append(graph.Graph, BuildBlogPosting(g.Config, data.Post, image))
+		name = data.Post.Title
+	}
+	if crumbs := BuildBreadcrumbs(g.Config, data.Path, name); crumbs != nil {
+		graph.Graph = append(graph.Graph, crumbs)
+	}
+
+	encoded, err := json.Marshal(graph)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(encoded), nil
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildBreadcrumbs(t *testing.T) {
+	cfg := createConfig()
+
+	tests := []struct {
+		name      string
+		path      string
+		wantNames []string
+	}{
+		{name: "Home Has No Trail", path: "index.html", wantNames: nil},
+		{name: "Top Level Page", path: "about.html", wantNames: []string{"Test Site", "About"}},
+		{name: "Blog Listing", path: "blog.html", wantNames: []string{"Test Site", "About"}},
+		{name: "Post Nested Under Blog", path: "blog/post.html", wantNames: []string{"Test Site", "Blog", "About"}},
+		{name: "Tag Nested Under Blog", path: "tags/go.html", wantNames: []string{"Test Site", "Blog", "About"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crumbs := BuildBreadcrumbs(cfg, tt.path, "About")
+			if tt.wantNames == nil {
+				if crumbs != nil {
+					t.Errorf("Expected no breadcrumbs, got %+v", crumbs)
+				}
+				return
+			}
+			if len(crumbs.ItemListElement) != len(tt.wantNames) {
+				t.Fatalf("Expected %d items, got %+v", len(tt.wantNames), crumbs.ItemListElement)
+			}
+			for i, item := range crumbs.ItemListElement {
+				if item.Position != i+1 {
+					t.Errorf("Item %d has position %d", i, item.Position)
+				}
+				if item.Name != tt.wantNames[i] {
+					t.Errorf("Item %d name = %q, want %q", i, item.Name, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStructuredData(t *testing.T) {
+	cfg := createConfig()
+	cfg.Landing.Name = "Tester"
+	cfg.Socials = []Social{
+		{Name: "GitHub", Href: "https://github.com/tester"},
+		{Name: "rss", Href: "/rss.xml"},
+	}
+	gen := New(cfg, "")
+
+	post := &Post{
+		Frontmatter: Frontmatter{
+			Title:       `Quotes "and" back\slashes </script>`,
+			Description: "Line one\nline two",
+			Date:        time.Date(2026, 6, 11, 0, 0, 0, 0, time.UTC),
+			Tags:        []string{"go", "json"},
+		},
+		Slug:      "tricky",
+		WordCount: 42,
+	}
+
+	encoded, err := gen.structuredData(PageData{Post: post, Path: "blog/tricky.html"}, post.Title)
+	if err != nil {
+		t.Fatalf("structuredData() error = %v", err)
+	}
+	if strings.Contains(string(encoded), "</script>") {
+		t.Error("Encoded JSON-LD must not contain a literal </script>")
+	}
+
+	var graph struct {
+		Graph []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.Unmarshal([]byte(encoded), &graph); err != nil {
+		t.Fatalf("Structured data is not valid JSON: %v\n%s", err, encoded)
+	}
+
+	types := map[string]map[string]interface{}{}
+	for _, node := range graph.Graph {
+		types[node["@type"].(string)] = node
+	}
+	for _, want := range []string{"WebSite", "Person", "BlogPosting", "BreadcrumbList"} {
+		if _, ok := types[want]; !ok {
+			t.Errorf("Expected %s node in graph", want)
+		}
+	}
+
+	posting := types["BlogPosting"]
+	if posting["headline"] != post.Title {
+		t.Errorf("Headline round-trip mismatch: %q", posting["headline"])
+	}
+	if posting["keywords"] != "go, json" {
+		t.Errorf("Unexpected keywords %q", posting["keywords"])
+	}
+	if posting["wordCount"] != float64(42) {
+		t.Errorf("Unexpected wordCount %v", posting["wordCount"])
+	}
+	if sameAs, _ := types["Person"]["sameAs"].([]interface{}); len(sameAs) != 1 {
+		t.Errorf("Expected only external socials in sameAs, got %v", types["Person"]["sameAs"])
+	}
+}
+
+func TestRenderPageStructuredData(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTemplates(t, tmpDir)
+	templatesDir := filepath.Join(tmpDir, "internal", "templates")
+	baseTmpl := `{{ define "base.html" }}<html><head><script type="application/ld+json">{{ .StructuredData }}</script></head><body>{{ template "content" . }}</body></html>{{ end }}`
+	if err := os.WriteFile(filepath.Join(templatesDir, "base.html"), []byte(baseTmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := New(createConfig(), templatesDir)
+	distDir := filepath.Join(tmpDir, "dist")
+	post := &Post{Frontmatter: Frontmatter{Title: `Say "hi"`}, Slug: "hi"}
+	if err := gen.RenderPage(distDir, "hi.html", "post.html", post.Title, PageData{Post: post, Path: "blog/hi.html"}); err != nil {
+		t.Fatalf("RenderPage() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(distDir, "hi.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(content)
+	// The minifier may drop attribute quotes, so locate the script body by its type.
+	start := strings.Index(html, "ld+json")
+	start += strings.Index(html[start:], ">") + 1
+	end := strings.Index(html, "</script>")
+	var v interface{}
+	if err := json.Unmarshal([]byte(html[start:end]), &v); err != nil {
+		t.Errorf("Rendered JSON-LD is invalid: %v\n%s", err, html[start:end])
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Template locations relative to the templates root. Page templates live at the root
+// and define "content"; layouts wrap a page and partials are fragments any page or
+// layout may include with {{ template "name" . }}.
+const (
+	defaultLayout = "base.html"
+	layoutsDir    = "layouts"
+	partialsDir   = "partials"
+)
+
+// templateSet holds every template parsed from a generator's templates, keyed by page.
+// Each page is a clone of the shared partials and layouts with the page parsed on top,
+// so a page may redefine a partial without affecting the others.
+type templateSet struct {
+	shared *template.Template
+
+	mu    sync.Mutex
+	pages map[string]*template.Template
+}
+
+// LoadTemplates parses the partials, layouts and page templates of g.Templates once,
+// replacing any set loaded before. Later definitions of the same name win, in the order
+// partials, layouts, page. Parse errors name the file and line they occurred on.
+func (g *SiteGenerator) LoadTemplates() error {
+	shared := template.New("").Funcs(g.FuncMap)
+	partials, err := globTemplates(g.Templates, partialsDir)
+	if err != nil {
+		return err
+	}
+	layouts, err := globTemplates(g.Templates, layoutsDir)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.Stat(g.Templates, defaultLayout); err == nil {
+		layouts = append([]string{defaultLayout}, layouts...)
+	}
+	for _, name := range append(partials, layouts...) {
+		if err := parseTemplateFile(shared, g.Templates, name); err != nil {
+			return err
+		}
+	}
+
+	pages, err := fs.Glob(g.Templates, "*.html")
+	if err != nil {
+		return fmt.Errorf("failed to list page templates: %w", err)
+	}
+	set := &templateSet{shared: shared, pages: make(map[string]*template.Template)}
+	for _, name := range pages {
+		if name == defaultLayout {
+			continue
+		}
+		if _, err := set.parsePage(g.Templates, name); err != nil {
+			return err
+		}
+	}
+	g.templates = set
+	return nil
+}
+
+// pageTemplate returns the parsed page template tmplPath, loading the template set on first
+// use. Pages outside the templates root are parsed when first asked for.
+func (g *SiteGenerator) pageTemplate(tmplPath string) (*template.Template, error) {
+	if g.templates == nil {
+		if err := g.LoadTemplates(); err != nil {
+			return nil, err
+		}
+	}
+	return g.templates.parsePage(g.Templates, tmplPath)
+}
+
+// parsePage returns the cached page template name, first parsing it over a clone of
+// the shared templates.
+func (s *templateSet) parsePage(fsys fs.FS, name string) (*template.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tmpl, ok := s.pages[name]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := s.shared.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone templates for %s: %w", name, err)
+	}
+	if err := parseTemplateFile(tmpl, fsys, name); err != nil {
+		return nil, err
+	}
+	s.pages[name] = tmpl
+	return tmpl, nil
+}
+
+// layoutTemplate returns the name of the layout a page is rendered with: base.html
+// unless the page asks for one of the layouts/ directory by its base name.
+func layoutTemplate(layout string) string {
+	if layout == "" {
+		return defaultLayout
+	}
+	return path.Join(layoutsDir, strings.TrimSuffix(layout, ".html")+".html")
+}
+
+// parseTemplateFile parses name from fsys into set as a template of the same name.
+func parseTemplateFile(set *template.Template, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", name, err)
+	}
+	if _, err := set.New(name).Parse(string(data)); err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+	return nil
+}
+
+// globTemplates returns every .html file below dir in fsys in lexical order, or
+// nothing when dir does not exist.
+func globTemplates(fsys fs.FS, dir string) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() && path.Ext(p) == ".html" {
+			names = append(names, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s templates: %w", dir, err)
+	}
+	return names, nil
+}
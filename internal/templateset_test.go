@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTemplateSetGenerator(t *testing.T, files map[string]string) (*SiteGenerator, *MemoryOutput) {
+	t.Helper()
+	templates := fstest.MapFS{}
+	for name, content := range files {
+		templates[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	gen := New(createConfig(), "")
+	gen.Templates = templates
+	out := NewMemoryOutput()
+	gen.Output = out
+	return gen, out
+}
+
+func TestRenderPageTemplates(t *testing.T) {
+	files := map[string]string{
+		"base.html":               `<html><body>{{ template "header" . }}{{ template "content" . }}</body></html>`,
+		"layouts/plain.html":      `<main>{{ template "content" . }}</main>`,
+		"partials/header.html":    `{{ define "header" }}<header>{{ .Config.Landing.Title }}</header>{{ end }}`,
+		"partials/cards/tag.html": `{{ define "tag" }}<span>#{{ .Tag }} in {{ .Prefix }}</span>{{ end }}`,
+		"index.html":              `{{ define "content" }}<div>{{ range .Tags }}{{ template "tag" dict "Tag" . "Prefix" $.PathPrefix }}{{ end }}</div>{{ end }}`,
+		"about.html":              `{{ define "header" }}<header>About</header>{{ end }}{{ define "content" }}<em>about</em>{{ end }}`,
+	}
+
+	tests := []struct {
+		name     string
+		tmplPath string
+		data     PageData
+		want     string
+	}{
+		{
+			name:     "Partials",
+			tmplPath: "index.html",
+			data:     PageData{Tags: []string{"go", "web"}, PathPrefix: "../"},
+			want:     "<header>Test Site</header><div><span>#go in ../</span><span>#web in ../</span></div>",
+		},
+		{
+			name:     "Page Overrides Partial",
+			tmplPath: "about.html",
+			want:     "<header>About</header><em>about</em>",
+		},
+		{
+			name:     "Layout",
+			tmplPath: "about.html",
+			data:     PageData{Layout: "plain"},
+			want:     "<main><em>about</em></main>",
+		},
+	}
+
+	gen, out := newTemplateSetGenerator(t, files)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := gen.RenderPage("dist", "page.html", tt.tmplPath, "", tt.data); err != nil {
+				t.Fatalf("RenderPage() error = %v", err)
+			}
+			got, err := out.ReadFile(filepath.Join("dist", "page.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("RenderPage() = %s, want it to contain %s", got, tt.want)
+			}
+		})
+	}
+
+	err := gen.RenderPage("dist", "page.html", "index.html", "", PageData{Layout: "missing"})
+	if err == nil || !strings.Contains(err.Error(), `unknown layout "missing"`) {
+		t.Errorf("Expected an unknown layout error, got %v", err)
+	}
+}
+
+func TestLoadTemplatesParsesOnce(t *testing.T) {
+	gen, out := newTemplateSetGenerator(t, map[string]string{
+		"base.html":  `{{ template "content" . }}`,
+		"index.html": `{{ define "content" }}<h1>{{ .Title }}</h1>{{ end }}`,
+	})
+	if err := gen.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	templates := gen.Templates.(fstest.MapFS)
+	templates["index.html"].Data = []byte(`{{ define "content" }}changed{{ end }}`)
+
+	first, err := gen.pageTemplate("index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.RenderPage("dist", "index.html", "index.html", "", PageData{}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := gen.pageTemplate("index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("Expected rendering to reuse the cached page template")
+	}
+	if _, err := second.Clone(); err != nil {
+		t.Errorf("Expected rendering to leave the cached template unexecuted: %v", err)
+	}
+	if got, _ := out.ReadFile(filepath.Join("dist", "index.html")); strings.Contains(string(got), "changed") {
+		t.Errorf("Expected the template to be parsed when loaded, not when rendered, got %s", got)
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "Page Syntax Error",
+			files: map[string]string{
+				"base.html": `{{ template "content" . }}`,
+				"post.html": "{{ define \"content\" }}\n<h1>\n{{ .Title }\n{{ end }}",
+			},
+			want: "post.html:3",
+		},
+		{
+			name: "Partial Unknown Function",
+			files: map[string]string{
+				"base.html":          `{{ template "content" . }}`,
+				"partials/card.html": "{{ define \"card\" }}\n{{ shout .Title }}{{ end }}",
+			},
+			want: `partials/card.html:2: function "shout" not defined`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, _ := newTemplateSetGenerator(t, tt.files)
+			err := gen.LoadTemplates()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadTemplates() error = %v, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// embeddedTheme holds the built-in page templates and partials, default content and
+// static assets. The compiled stylesheet is committed with the other static files and
+// regenerated by `go generate` or the tailwind build hook. The repository's
+// hooks.yaml is left out because its hooks only work inside this checkout.
+//
+//go:generate tailwindcss -i templates/input.css -o templates/static/styles.css --minify
+//go:embed templates/*.html templates/partials templates/contents/config.yaml templates/contents/projects.yaml templates/static
+var embeddedTheme embed.FS
+
+// DefaultTheme returns the built-in theme compiled into the binary: page templates at
+// its root, default content under contents/ and static assets under static/.
+func DefaultTheme() fs.FS {
+	theme, err := fs.Sub(embeddedTheme, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return theme
+}
+
+// Theme supplies the templates, content and static files a site does not provide
+// itself. Files in Dir replace the site's own files with the same path, which in turn
+// replace those in Base. Both are laid out like DefaultTheme; either may be empty.
+type Theme struct {
+	Dir  string
+	Base fs.FS
+}
+
+// Layer returns the files of the site directory dir, overridden by the sub directory
+// of Dir and completed by the sub directory of Base.
+func (t Theme) Layer(dir, sub string) fs.FS {
+	var layers OverlayFS
+	if t.Dir != "" {
+		layers = append(layers, os.DirFS(filepath.Join(t.Dir, filepath.FromSlash(sub))))
+	}
+	layers = append(layers, os.DirFS(dir))
+	if t.Base != nil {
+		base, err := fs.Sub(t.Base, sub)
+		if err != nil {
+			panic(err)
+		}
+		layers = append(layers, base)
+	}
+	if len(layers) == 1 {
+		return layers[0]
+	}
+	return layers
+}
+
+// OverlayFS is a read-only union of file systems in which earlier layers win: a file
+// is read from the first layer that has it, and a directory lists the entries of
+// every layer that has it. A file in one layer hides a directory of the same name,
+// and everything in it, in the layers below.
+type OverlayFS []fs.FS
+
+func (o OverlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, layer, err := o.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return layer.Open(name)
+	}
+	entries, err := o.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &listedDir{info: info, entries: entries}, nil
+}
+
+func (o OverlayFS) Stat(name string) (fs.FileInfo, error) {
+	info, _, err := o.stat(name)
+	return info, err
+}
+
+// stat returns the file info of name in the first layer that has it.
+func (o OverlayFS) stat(name string) (fs.FileInfo, fs.FS, error) {
+	for _, layer := range o {
+		info, err := fs.Stat(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if shadowed(layer, name) {
+				break
+			}
+			continue
+		}
+		return info, layer, err
+	}
+	return nil, nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	found := false
+	for _, layer := range o {
+		info, err := fs.Stat(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			if shadowed(layer, name) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			break
+		}
+		found = true
+		list, err := fs.ReadDir(layer, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// shadowed reports whether the closest parent of name that exists in layer is a file,
+// which hides name in the layers below.
+func shadowed(layer fs.FS, name string) bool {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if info, err := fs.Stat(layer, dir); err == nil {
+			return !info.IsDir()
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultTheme(t *testing.T) {
+	theme := DefaultTheme()
+
+	for _, name := range []string{"base.html", "index.html", "post.html", "partials/header.html", "contents/config.yaml", "contents/projects.yaml", "static/robots.txt", "static/styles.css"} {
+		if _, err := fs.Stat(theme, name); err != nil {
+			t.Errorf("Expected %s in the default theme: %v", name, err)
+		}
+	}
+	if _, err := fs.Stat(theme, "input.css"); err == nil {
+		t.Error("Expected the Tailwind input to stay out of the default theme")
+	}
+
+	cfg, err := LoadConfigFS(Theme{Base: theme}.Layer(t.TempDir(), "contents"))
+	if err != nil {
+		t.Fatalf("Failed to load the default content: %v", err)
+	}
+	if cfg.Landing.Title == "" {
+		t.Error("Expected the default content to set a site title")
+	}
+	if len(cfg.Hooks.PreBuild) > 0 || len(cfg.Hooks.PostBuild) > 0 {
+		t.Errorf("Expected the default content to run no build hooks, got %+v", cfg.Hooks)
+	}
+
+	gen := New(cfg, "")
+	gen.Templates = theme
+	if err := gen.LoadTemplates(); err != nil {
+		t.Fatalf("Failed to parse the embedded templates: %v", err)
+	}
+	if len(gen.templates.pages) == 0 {
+		t.Error("Expected the embedded theme to provide page templates")
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	upper := fstest.MapFS{
+		"post.html":         {Data: []byte("upper post")},
+		"static/site.css":   {Data: []byte("upper css")},
+		"contents/projects": {Data: []byte("a file hiding the lower directory")},
+	}
+	lower := fstest.MapFS{
+		"post.html":                 {Data: []byte("lower post")},
+		"base.html":                 {Data: []byte("lower base")},
+		"static/robots.txt":         {Data: []byte("lower robots")},
+		"contents/projects/a.yaml":  {Data: []byte("hidden")},
+		"contents/config.yaml":      {Data: []byte("lower config")},
+		"static/icons/github.svg":   {Data: []byte("<svg/>")},
+		"static/icons/linkedin.svg": {Data: []byte("<svg/>")},
+	}
+	overlay := OverlayFS{upper, lower}
+
+	if err := fstest.TestFS(overlay, "post.html", "base.html", "static/site.css", "static/robots.txt", "static/icons/github.svg"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"post.html", "upper post"},
+		{"base.html", "lower base"},
+		{"static/site.css", "upper css"},
+		{"static/robots.txt", "lower robots"},
+		{"contents/projects", "a file hiding the lower directory"},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(overlay, tt.name)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", tt.name, data, err, tt.want)
+		}
+	}
+
+	entries, err := fs.ReadDir(overlay, "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"icons", "robots.txt", "site.css"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir(static) = %v, want %v", names, want)
+	}
+	if _, err := fs.Stat(overlay, "contents/projects/a.yaml"); err == nil {
+		t.Error("Expected a file in an upper layer to hide the directory below it")
+	}
+	if _, err := overlay.Open("missing.html"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+func TestThemeLayer(t *testing.T) {
+	siteDir := writeTree(t, map[string]string{
+		"post.html": "site post",
+		"blog.html": "site blog",
+	})
+	themeDir := writeTree(t, map[string]string{
+		"post.html":         "theme post",
+		"static/robots.txt": "theme robots",
+	})
+	base := fstest.MapFS{
+		"post.html":         {Data: []byte("base post")},
+		"blog.html":         {Data: []byte("base blog")},
+		"index.html":        {Data: []byte("base index")},
+		"static/robots.txt": {Data: []byte("base robots")},
+	}
+
+	templates := Theme{Dir: themeDir, Base: base}.Layer(siteDir, ".")
+	for name, want := range map[string]string{"post.html": "theme post", "blog.html": "site blog", "index.html": "base index"} {
+		if data, err := fs.ReadFile(templates, name); err != nil || string(data) != want {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	static := Theme{Dir: themeDir, Base: base}.Layer(filepath.Join(siteDir, "static"), "static")
+	if data, err := fs.ReadFile(static, "robots.txt"); err != nil || string(data) != "theme robots" {
+		t.Errorf("Expected the theme dir to override static files, got %q, %v", data, err)
+	}
+
+	if _, ok := (Theme{}).Layer(siteDir, ".").(OverlayFS); ok {
+		t.Error("Expected an empty theme to read the site directory directly")
+	}
+}
+
+func TestRunPipelineWithTheme(t *testing.T) {
+	distDir, configDir, templatesDir, blogDir, publicDir := pipelineFixture(t)
+	if err := os.Remove(filepath.Join(templatesDir, "work.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(configDir, "projects.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	themeDir := writeTree(t, map[string]string{
+		"about.html": `{{ define "content" }}<h1>Themed {{ .Title }}</h1>{{ end }}`,
+	})
+	base := fstest.MapFS{
+		"work.html":              {Data: []byte(`{{ define "content" }}<h1>Base work</h1>{{ end }}`)},
+		"contents/projects.yaml": {Data: []byte("projects: []")},
+		"static/humans.txt":      {Data: []byte("built-in")},
+	}
+
+	out := NewMemoryOutput()
+	theme := Theme{Dir: themeDir, Base: base}
+	if _, err := RunPipeline(distDir, configDir, templatesDir, blogDir, publicDir, WithOutput(out), WithTheme(theme)); err != nil {
+		t.Fatalf("RunPipeline failed: %v", err)
+	}
+
+	site := out.FS(distDir)
+	checks := map[string]string{
+		"about.html": "Themed About",
+		"work.html":  "Base work",
+		"index.html": "Integration Test Site",
+		"humans.txt": "built-in",
+		"test.txt":   "assets",
+	}
+	for name, want := range checks {
+		data, err := fs.ReadFile(site, name)
+		if err != nil {
+			t.Errorf("Expected %s in the build: %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s to contain %q, got %s", name, want, data)
+		}
+	}
+}